@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -39,22 +40,619 @@ type MetricsConfig struct {
 	PrometheusURL string          `json:"prometheusUrl,omitempty"`
 	ErrorRate     MetricThreshold `json:"errorRate,omitempty"`
 	Latency       MetricThreshold `json:"latency,omitempty"`
+
+	// AuthSecretRef names a Secret in the same namespace with the credentials for Prometheus:
+	// a bearer token under the "token" key, or "username" and "password" for basic auth
+	// +optional
+	AuthSecretRef *corev1.LocalObjectReference `json:"authSecretRef,omitempty"`
+
+	// InconclusiveLimit is how many analyses of a step in a row may be inconclusive before
+	// the rollout is rolled back (defaults to 5)
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	InconclusiveLimit *int32 `json:"inconclusiveLimit,omitempty"`
+}
+
+// Strategies supported by the controller
+const (
+	// StrategyCanary shifts traffic gradually by splitting replicas between stable and canary
+	StrategyCanary = "canary"
+	// StrategyBlueGreen runs a full-size green Deployment next to blue and switches the active Service at once
+	StrategyBlueGreen = "blueGreen"
+)
+
+// Rounding modes for the canary's share of the replicas
+const (
+	// RoundingCeil rounds up, so every non-zero weight gets at least one canary replica
+	RoundingCeil = "ceil"
+	// RoundingFloor rounds down, so the canary never gets more than its weight
+	RoundingFloor = "floor"
+	// RoundingNearest rounds to the closest replica count
+	RoundingNearest = "nearest"
+)
+
+// BlueGreenStrategy configures the blueGreen strategy
+type BlueGreenStrategy struct {
+	// ActiveService is the Service that receives production traffic
+	ActiveService string `json:"activeService"`
+
+	// PreviewService is pointed at green while it is analyzed.
+	// It is created from ActiveService when it does not exist (defaults to <activeService>-preview)
+	// +optional
+	PreviewService string `json:"previewService,omitempty"`
+
+	// ScaleDownDelay is how long blue is kept at full size after the switch-over,
+	// so the active Service can be switched back instantly
+	// +optional
+	ScaleDownDelay *metav1.Duration `json:"scaleDownDelay,omitempty"`
 }
 
 // ProgressiveDeploymentSpec defines the desired state of ProgressiveDeployment
+// +kubebuilder:validation:XValidation:rule="(has(self.targetDeployment) && size(self.targetDeployment) > 0) != has(self.targetRef)",message="exactly one of targetDeployment and targetRef must be set"
+// +kubebuilder:validation:XValidation:rule="!has(self.steps) || !has(self.canarySteps)",message="canarySteps and steps are mutually exclusive"
+// +kubebuilder:validation:XValidation:rule="!has(self.steps) || has(self.trafficRouting) || !self.steps.exists(s, has(s.setHeaderRoute))",message="setHeaderRoute steps need a trafficRouting router supporting match rules, the replica-ratio router cannot route by header"
+// +kubebuilder:validation:XValidation:rule="!has(self.steps) || has(self.trafficRouting) || !self.steps.exists(s, has(s.setMirrorRoute))",message="setMirrorRoute steps need a trafficRouting router supporting mirroring, the replica-ratio router cannot mirror traffic"
 type ProgressiveDeploymentSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 	// The following markers will use OpenAPI v3 schema to validate the value
 	// More info: https://book.kubebuilder.io/reference/markers/crd-validation.html
 
-	// foo is an example field of ProgressiveDeployment. Edit progressivedeployment_types.go to remove/update
+	// TargetDeployment is the name of the Deployment to roll out.
+	// It is shorthand for a targetRef of kind Deployment
+	// +optional
+	TargetDeployment string `json:"targetDeployment"`
+
+	// TargetRef references the workload to roll out, a Deployment or a StatefulSet
+	// +optional
+	TargetRef *WorkloadRef `json:"targetRef,omitempty"`
+
+	// Service is the primary Service in front of the target. When set, the controller keeps
+	// <service>-stable and <service>-canary Services next to it, each pinned to the pods of
+	// one version, so routers, metric queries and smoke tests can address a version explicitly
+	// +optional
+	Service string `json:"service,omitempty"`
+
+	// TrafficRouting selects a router splitting requests between the stable and canary
+	// Services (canary strategy only, requires service). Without it, traffic follows the
+	// ratio of stable to canary replicas
+	// +optional
+	TrafficRouting *TrafficRouting `json:"trafficRouting,omitempty"`
+
+	// DynamicStableScale shrinks stable as the canary grows when a router splits the
+	// traffic, so stable and canary together run the target's replicas. By default stable
+	// keeps its full size until promotion and the canary runs as surge capacity, so a
+	// rollback only has to shift traffic back. Without trafficRouting the replica ratio is
+	// the traffic split, so stable always shrinks
+	// +optional
+	DynamicStableScale bool `json:"dynamicStableScale,omitempty"`
+
+	// TrackLabelKey is the pod label that tells the new version's pods apart from the
+	// stable ones. The new version's selector requires it, so it never matches stable pods.
+	// Include it in the target's selector (e.g. with the value "stable") to keep the
+	// stable selector from matching the new version's pods as well
+	// +kubebuilder:default="bgswitch.io/track"
+	// +kubebuilder:validation:MaxLength=317
+	// +optional
+	TrackLabelKey string `json:"trackLabelKey,omitempty"`
+
+	// Strategy selects how the new version is rolled out
+	// +kubebuilder:validation:Enum=canary;blueGreen
+	// +kubebuilder:default=canary
+	// +optional
+	Strategy string `json:"strategy,omitempty"`
+
+	// GitOps leaves the target workload to a GitOps tool such as Argo CD or Flux: bg-switch
+	// never writes to it and runs the stable version in a <target>-stable workload it manages
+	// instead. Keep the target at zero replicas in Git and declare the size of the managed
+	// workloads in its bgswitch.io/replicas annotation
+	// +optional
+	GitOps bool `json:"gitOps,omitempty"`
+
+	// DryRun plans the rollout without touching anything: the replicas of each step and the
+	// current metric values against their thresholds are reported in status.plan and events.
+	// It applies before a rollout starts and once it has finished, a rollout in flight keeps running
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// Canary configures the canary workload (canary strategy only)
+	// +optional
+	Canary *CanaryOptions `json:"canary,omitempty"`
+
+	// CanarySteps are the canary traffic percentages (canary strategy only)
+	// +optional
+	CanarySteps []int `json:"canarySteps,omitempty"`
+
+	// Steps replace canarySteps when a step does more than set a weight (canary strategy only).
+	// Each step is analyzed like a canary step
+	// +optional
+	Steps []CanaryStep `json:"steps,omitempty"`
+
+	StepDuration metav1.Duration `json:"stepDuration"`
+	Metrics      MetricsConfig   `json:"metrics"`
+	AutoPromote  bool            `json:"autoPromote"`
+
+	// BlueGreen configures the blueGreen strategy
+	// +optional
+	BlueGreen *BlueGreenStrategy `json:"blueGreen,omitempty"`
+
+	// RevisionHistoryLimit is the number of finished rollouts kept in status.history
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=10
+	// +optional
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// RollbackTo restarts the rollout from the template of a past revision. The controller
+	// acts on it once and records it in status.rolledBackTo, it never clears it
+	// +optional
+	RollbackTo *RollbackConfig `json:"rollbackTo,omitempty"`
+
+	// RestartAt restarts a Completed, RolledBack or Failed rollout once this time has passed.
+	// Setting it to a newer time retries again
+	// +optional
+	RestartAt *metav1.Time `json:"restartAt,omitempty"`
+
+	// RestartRequest restarts a Completed, RolledBack or Failed rollout right away each time it
+	// is increased. bgswitch retry increments it, so retries in the same second are not lost
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	RestartRequest int64 `json:"restartRequest,omitempty"`
+
+	// RestartFromStep is the step index (0-based) a restarted rollout begins at
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	RestartFromStep int `json:"restartFromStep,omitempty"`
+
+	// Schedule restricts when traffic may be shifted. Outside an allowed window the
+	// rollout holds its current step until the window opens
+	// +optional
+	Schedule *RolloutSchedule `json:"schedule,omitempty"`
+
+	// Notifications send rollout events to chat channels and webhooks
+	// +optional
+	Notifications []NotificationSpec `json:"notifications,omitempty"`
+}
+
+// CanaryOptions configures the canary workload
+type CanaryOptions struct {
+	// Scheduling overrides where the canary pods are scheduled
+	// +optional
+	Scheduling *SchedulingOverride `json:"scheduling,omitempty"`
+
+	// MinReplicas is the fewest replicas the canary runs at a non-zero weight, so a small
+	// target still gets a canary at low weights whatever the rounding
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinReplicas int32 `json:"minReplicas,omitempty"`
+
+	// MaxSurge is how many canary replicas run on top of stable instead of being taken from
+	// it when traffic follows the replica ratio, so stable keeps its capacity at low weights
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxSurge int32 `json:"maxSurge,omitempty"`
+
+	// Rounding selects how a step's share of the replicas is rounded to whole canary replicas
+	// +kubebuilder:validation:Enum=ceil;floor;nearest
+	// +kubebuilder:default=ceil
+	// +optional
+	Rounding string `json:"rounding,omitempty"`
+}
+
+// SchedulingOverride replaces scheduling fields of the cloned pod template. Fields that are
+// not set are copied from the target, with pod (anti-)affinity and topology spread selectors
+// that refer to the stable pods rewritten to refer to the canary pods
+type SchedulingOverride struct {
+	// NodeSelector replaces the pod template's node selector, e.g. to pin canaries to a node pool
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations replace the pod template's tolerations
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Affinity replaces the pod template's affinity
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// TopologySpreadConstraints replace the pod template's topology spread constraints
+	// +optional
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+
+	// PriorityClassName replaces the pod template's priority class
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+}
+
+// CanaryStep is one step of the canary strategy. Exactly one field must be set
+// +kubebuilder:validation:XValidation:rule="[has(self.setWeight), has(self.setHeaderRoute), has(self.setMirrorRoute)].filter(x, x).size() == 1",message="exactly one step kind must be set"
+type CanaryStep struct {
+	// SetWeight sends this percentage of the traffic to the canary
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	SetWeight *int `json:"setWeight,omitempty"`
+
+	// SetHeaderRoute sends requests matching a header or cookie to the canary, so testers can
+	// reach it before or besides the weighted traffic. The weight of the previous step (0 for
+	// the first) is kept, and the route stays in place until the rollout finishes
+	// +optional
+	SetHeaderRoute *HeaderRoute `json:"setHeaderRoute,omitempty"`
+
+	// SetMirrorRoute mirrors a percentage of the requests to the canary and discards its
+	// responses, keeping the weight of the previous step. The mirror only lasts for this
+	// step, so analysis of the mirrored traffic must pass before the next step begins
+	// +optional
+	SetMirrorRoute *MirrorRoute `json:"setMirrorRoute,omitempty"`
+}
+
+// MirrorRoute configures a setMirrorRoute step
+type MirrorRoute struct {
+	// Percentage of the requests of the routed routes that is mirrored to the canary
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	Percentage int `json:"percentage"`
+}
+
+// HeaderRoute selects the requests a setHeaderRoute step sends to the canary. Exactly one of header and cookie must be set
+// +kubebuilder:validation:XValidation:rule="has(self.header) != has(self.cookie)",message="exactly one of header and cookie must be set"
+type HeaderRoute struct {
+	// Header matches requests carrying this header with the given value
+	// +optional
+	Header *RequestMatch `json:"header,omitempty"`
+
+	// Cookie matches requests carrying this cookie with the given value
+	// +optional
+	Cookie *RequestMatch `json:"cookie,omitempty"`
+}
+
+// RequestMatch matches a named request attribute against an exact value
+type RequestMatch struct {
+	// Name of the header or cookie
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Value the header or cookie must have
+	// +kubebuilder:validation:MinLength=1
+	Value string `json:"value"`
+}
+
+// TrafficRouting configures the router shifting traffic to the canary. Exactly one router must be set
+// +kubebuilder:validation:XValidation:rule="has(self.nginx) != has(self.istio)",message="exactly one router must be set"
+type TrafficRouting struct {
+	// Nginx shifts traffic with an ingress-nginx canary Ingress
+	// +optional
+	Nginx *NginxTrafficRouting `json:"nginx,omitempty"`
+
+	// Istio shifts traffic by weighting routes of an Istio VirtualService
+	// +optional
+	Istio *IstioTrafficRouting `json:"istio,omitempty"`
+}
+
+// NginxTrafficRouting configures the ingress-nginx router. The stable Ingress is cloned into
+// <stableIngress>-canary, routing to <service>-canary with the canary-weight annotation set
+// to the current step. The canary Ingress is deleted on completion or rollback
+type NginxTrafficRouting struct {
+	// StableIngress is the Ingress routing to spec.service
+	// +kubebuilder:validation:MinLength=1
+	StableIngress string `json:"stableIngress"`
+
+	// Header sends requests carrying it to the canary whatever the weight: with the value
+	// "always", or with HeaderValue when set. "never" sends them to stable
+	// +optional
+	Header string `json:"header,omitempty"`
+
+	// HeaderValue is the value of Header that selects the canary
+	// +optional
+	HeaderValue string `json:"headerValue,omitempty"`
+
+	// Cookie sends requests to the canary when the cookie is set to "always",
+	// and to stable when it is set to "never"
+	// +optional
+	Cookie string `json:"cookie,omitempty"`
+}
+
+// IstioTrafficRouting configures the Istio router. The destinations of the named routes are
+// replaced with the stable and canary versions, weighted by the current step. Without a
+// DestinationRule the versions are the <service>-stable and <service>-canary hosts
+type IstioTrafficRouting struct {
+	// VirtualService holds the routes to weight
+	VirtualService IstioVirtualService `json:"virtualService"`
+
+	// DestinationRule routes to subsets of the spec.service host instead. The controller
+	// pins the stable and canary subsets to the pods of each version
+	// +optional
+	DestinationRule *IstioDestinationRule `json:"destinationRule,omitempty"`
+}
+
+// IstioVirtualService references a VirtualService and the HTTP routes the controller owns
+type IstioVirtualService struct {
+	// Name of the VirtualService
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Routes are the names of the HTTP routes whose destinations are weighted.
+	// Other routes, and the match rules of these, are left untouched
+	// +kubebuilder:validation:MinItems=1
+	Routes []string `json:"routes"`
+}
+
+// IstioDestinationRule references a DestinationRule and the subsets the controller owns
+type IstioDestinationRule struct {
+	// Name of the DestinationRule
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// StableSubsetName is the subset selecting the stable pods
+	// +kubebuilder:default=stable
+	// +optional
+	StableSubsetName string `json:"stableSubsetName,omitempty"`
+
+	// CanarySubsetName is the subset selecting the new version's pods
+	// +kubebuilder:default=canary
+	// +optional
+	CanarySubsetName string `json:"canarySubsetName,omitempty"`
+}
+
+// Kinds of workload a ProgressiveDeployment can target
+const (
+	KindDeployment  = "Deployment"
+	KindStatefulSet = "StatefulSet"
+)
+
+// WorkloadRef references a workload in the same namespace
+type WorkloadRef struct {
+	// APIVersion of the workload
+	// +kubebuilder:validation:Enum=apps/v1
+	// +kubebuilder:default="apps/v1"
+	// +optional
+	APIVersion string `json:"apiVersion,omitempty"`
+
+	// Kind of the workload
+	// +kubebuilder:validation:Enum=Deployment;StatefulSet
+	Kind string `json:"kind"`
+
+	// Name of the workload
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// NotificationTrigger is a rollout event that can be sent as a notification
+// +kubebuilder:validation:Enum=RolloutStarted;StepPromoted;RolloutCompleted;RolloutRolledBack;RolloutFailed
+type NotificationTrigger string
+
+const (
+	TriggerRolloutStarted    NotificationTrigger = "RolloutStarted"
+	TriggerStepPromoted      NotificationTrigger = "StepPromoted"
+	TriggerRolloutCompleted  NotificationTrigger = "RolloutCompleted"
+	TriggerRolloutRolledBack NotificationTrigger = "RolloutRolledBack"
+	TriggerRolloutFailed     NotificationTrigger = "RolloutFailed"
+)
+
+// NotificationSpec configures one notification sink
+type NotificationSpec struct {
+	// Name identifies the notification in logs
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Type selects the payload format: a generic JSON webhook, a Slack incoming
+	// webhook or an MS Teams incoming webhook
+	// +kubebuilder:validation:Enum=webhook;slack;teams
+	// +kubebuilder:default=webhook
+	// +optional
+	Type string `json:"type,omitempty"`
+
+	// Triggers lists the events to send. All events are sent when empty
+	// +optional
+	Triggers []NotificationTrigger `json:"triggers,omitempty"`
+
+	// Template is a Go text/template rendering the message. It is executed with the
+	// event fields: Trigger, Name, Namespace, Phase, Step, CanaryPercentage and Message
+	// +optional
+	Template string `json:"template,omitempty"`
+
+	// SecretRef names a Secret in the same namespace holding the webhook URL under
+	// the "url" key and, for generic webhooks, an optional bearer token under "token"
+	SecretRef corev1.LocalObjectReference `json:"secretRef"`
+}
+
+// ConditionSelectorsDisjoint is True when the stable and new version selectors cannot select the same pod
+const ConditionSelectorsDisjoint = "SelectorsDisjoint"
+
+// ConditionOutsideWindow is True while the rollout is held by spec.schedule
+const ConditionOutsideWindow = "OutsideWindow"
+
+// ConditionRevisionRestored is True when the last spec.rollbackTo restarted the rollout
+// from its revision, and False when the revision could not be restored
+const ConditionRevisionRestored = "RevisionRestored"
+
+// ConditionTargetScaledDown is True in GitOps mode once the target runs no replicas,
+// leaving the traffic to the workloads managed by bg-switch
+const ConditionTargetScaledDown = "TargetScaledDown"
+
+// RolloutSchedule defines when traffic may be shifted
+type RolloutSchedule struct {
+	// TimeZone is the IANA time zone windows are evaluated in (defaults to UTC)
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Windows are the recurring periods traffic may be shifted in. No windows means any time
+	// +optional
+	Windows []ScheduleWindow `json:"windows,omitempty"`
+
+	// Blackouts are change freezes during which traffic must not be shifted, even inside a window
+	// +optional
+	Blackouts []BlackoutRange `json:"blackouts,omitempty"`
+
+	// CalendarRef references a ConfigMap holding a shared schedule under the "schedule" key.
+	// Traffic is only shifted when both this schedule and the shared one allow it
+	// +optional
+	CalendarRef *CalendarReference `json:"calendarRef,omitempty"`
+}
+
+// ScheduleWindow is a recurring daily period on the given days
+type ScheduleWindow struct {
+	// Days the window starts on; empty means every day
+	// +optional
+	Days []ScheduleDay `json:"days,omitempty"`
+
+	// Start is the local time the window opens, as HH:MM
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+
+	// End is the local time the window closes, as HH:MM. An End before Start spans midnight
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	End string `json:"end"`
+}
+
+// ScheduleDay is a day of the week
+// +kubebuilder:validation:Enum=Mon;Tue;Wed;Thu;Fri;Sat;Sun
+type ScheduleDay string
+
+// BlackoutRange is a change freeze
+type BlackoutRange struct {
+	// Name describes the freeze
+	// +optional
+	Name string `json:"name,omitempty"`
+	// Start is when the freeze begins
+	Start metav1.Time `json:"start"`
+	// End is when the freeze ends
+	End metav1.Time `json:"end"`
+}
+
+// CalendarReference points at a ConfigMap holding a shared RolloutSchedule
+type CalendarReference struct {
+	// Name of the ConfigMap
+	Name string `json:"name"`
+	// Namespace of the ConfigMap (defaults to the ProgressiveDeployment namespace)
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// RollbackConfig selects the revision to restart the rollout from
+type RollbackConfig struct {
+	// Revision is the status.history revision whose template is rolled out again
+	// +kubebuilder:validation:Minimum=1
+	Revision int64 `json:"revision"`
+	// RequestedAt tells repeated requests for the same revision apart,
+	// setting it to a newer time rolls back to the revision again
+	// +optional
+	RequestedAt *metav1.MicroTime `json:"requestedAt,omitempty"`
+}
+
+// StepResult records the analysis outcome of one step
+type StepResult struct {
+	// Step is the step index (0-based)
+	Step int `json:"step"`
+	// CanaryPercentage is the traffic percentage the step was analyzed at
+	CanaryPercentage int `json:"canaryPercentage"`
+	// Healthy is the outcome of the analysis
+	Healthy bool `json:"healthy"`
+	// Metrics contains the observed metric values
+	// +optional
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+	// Message explains an analysis error
+	// +optional
+	Message string `json:"message,omitempty"`
+	// AnalyzedAt is when the analysis ran
+	AnalyzedAt metav1.Time `json:"analyzedAt"`
+}
+
+// RolloutRevision records one finished rollout attempt
+type RolloutRevision struct {
+	// Revision is a sequence number, increasing with every attempt
+	Revision int64 `json:"revision"`
+	// TemplateHash identifies the pod template that was rolled out
+	TemplateHash string `json:"templateHash"`
+	// Image lists the container images of the rolled out template
+	// +optional
+	Image string `json:"image,omitempty"`
+	// StartedAt is when the rollout started
+	// +optional
+	StartedAt *metav1.Time `json:"startedAt,omitempty"`
+	// FinishedAt is when the rollout reached its final phase
+	// +optional
+	FinishedAt *metav1.Time `json:"finishedAt,omitempty"`
+	// Phase is the final phase (Completed or RolledBack)
+	Phase string `json:"phase"`
+	// Steps are the per-step analysis results
+	// +optional
+	Steps []StepResult `json:"steps,omitempty"`
+	// RollbackReason explains why the rollout was rolled back
+	// +optional
+	RollbackReason string `json:"rollbackReason,omitempty"`
+}
+
+// AutoscalerStatus holds the original bounds of a HorizontalPodAutoscaler
+type AutoscalerStatus struct {
+	// Name of the HorizontalPodAutoscaler targeting the stable workload
+	Name string `json:"name"`
+	// MinReplicas before the rollout started
+	MinReplicas int32 `json:"minReplicas"`
+	// MaxReplicas before the rollout started
+	MaxReplicas int32 `json:"maxReplicas"`
+}
+
+// Verdicts of a planned analysis
+const (
+	// VerdictPromote means every metric is within its threshold
+	VerdictPromote = "Promote"
+	// VerdictRollBack means a metric exceeded its threshold or could not be queried
+	VerdictRollBack = "RollBack"
+	// VerdictInconclusive means no metric failed but a query did not complete in time
+	VerdictInconclusive = "Inconclusive"
+)
+
+// PlannedStep is the replica split a step of a planned rollout would run
+type PlannedStep struct {
+	// Step is the index of the step
+	Step int `json:"step"`
+	// Weight is the traffic percentage the step requests for the canary
+	Weight int `json:"weight"`
+	// StableReplicas is the size the stable workload would have
+	StableReplicas int32 `json:"stableReplicas"`
+	// CanaryReplicas is the size the canary workload would have
+	CanaryReplicas int32 `json:"canaryReplicas"`
+	// EffectivePercentage is the traffic percentage the canary would actually get
+	EffectivePercentage int `json:"effectivePercentage"`
+}
+
+// PlannedCheck is a metric query of a planned rollout evaluated against its threshold
+type PlannedCheck struct {
+	// Name is the metric checked (errorRate or latency)
+	Name string `json:"name"`
+	// Query is the PromQL query
+	Query string `json:"query"`
+	// Threshold is the maximum acceptable value
+	// +kubebuilder:validation:Type=number
+	Threshold float64 `json:"threshold"`
+	// Value is the current result of the query, unset when the query failed
+	// +kubebuilder:validation:Type=number
+	// +optional
+	Value *float64 `json:"value,omitempty"`
+	// Passed reports whether the value is within the threshold
+	Passed bool `json:"passed"`
+	// Error is why the query failed
+	// +optional
+	Error string `json:"error,omitempty"`
+	// Inconclusive reports whether the query did not complete in time
+	// +optional
+	Inconclusive bool `json:"inconclusive,omitempty"`
+}
+
+// RolloutPlan is what a rollout would do, reported in spec.dryRun mode
+type RolloutPlan struct {
+	// PlannedAt is when the plan was computed
+	PlannedAt metav1.Time `json:"plannedAt"`
+	// Replicas is the size of the target the plan starts from
+	Replicas int32 `json:"replicas"`
+	// Steps are the replica splits of each step
+	Steps []PlannedStep `json:"steps,omitempty"`
+	// Checks are the configured metrics evaluated now
 	// +optional
-	TargetDeployment string          `json:"targetDeployment"`
-	CanarySteps      []int           `json:"canarySteps"`
-	StepDuration     metav1.Duration `json:"stepDuration"`
-	Metrics          MetricsConfig   `json:"metrics"`
-	AutoPromote      bool            `json:"autoPromote"`
+	Checks []PlannedCheck `json:"checks,omitempty"`
+	// Verdict is what an analysis would decide with the current metric values
+	// +kubebuilder:validation:Enum=Promote;RollBack;Inconclusive
+	Verdict string `json:"verdict"`
 }
 
 // ProgressiveDeploymentStatus defines the observed state of ProgressiveDeployment.
@@ -79,10 +677,15 @@ type ProgressiveDeploymentStatus struct {
 	Phase string `json:"phase,omitempty"`
 	// CurrentStep is the current canary step index (0-based)
 	CurrentStep int `json:"currentStep,omitempty"`
-	// CanaryPercentage is the current traffic percentage going to canary
+	// CanaryPercentage is the traffic percentage the current step requests for the canary
 	CanaryPercentage int `json:"canaryPercentage,omitempty"`
+	// EffectivePercentage is the traffic percentage the canary actually gets. With a traffic
+	// router it is the requested weight, otherwise the canary's share of the replicas
+	EffectivePercentage int `json:"effectivePercentage,omitempty"`
 	// CanaryDeployment is the name of the canary Deployment
 	CanaryDeployment string `json:"canaryDeployment,omitempty"`
+	// StableDeployment is the name of the stable workload managed in GitOps mode
+	StableDeployment string `json:"stableDeployment,omitempty"`
 	// HealthStatus indicates if the canary is healthy
 	// +kubebuilder:validation:Enum=Healthy;Unhealthy;Unknown
 	HealthStatus string `json:"healthStatus,omitempty"`
@@ -92,14 +695,53 @@ type ProgressiveDeploymentStatus struct {
 	// Conditions represent the latest available observations
 	Conditions       []metav1.Condition `json:"conditions,omitempty"`
 	LastAnalysisTime *metav1.Time       `json:"lastAnalysisTime,omitempty"`
+	// StableSelector is the active Service selector pinned to the blue pods (blueGreen only)
+	StableSelector map[string]string `json:"stableSelector,omitempty"`
+	// SwitchedAt is when the active Service was switched over to green (blueGreen only)
+	SwitchedAt *metav1.Time `json:"switchedAt,omitempty"`
+	// Autoscaler records the bounds of the target's HorizontalPodAutoscaler before the
+	// rollout split them, so they can be restored on completion or rollback
+	Autoscaler *AutoscalerStatus `json:"autoscaler,omitempty"`
+	// StableReplicas is the size of the target when a rollout with dynamicStableScale
+	// started, restored on rollback and given to the canary on promotion
+	StableReplicas *int32 `json:"stableReplicas,omitempty"`
+	// ObservedGeneration is the most recent generation of the spec the controller has acted on
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// ObservedSteps are the step weights the rollout in flight is running
+	ObservedSteps []int `json:"observedSteps,omitempty"`
+	// ObservedMetricsHash is the hash of spec.metrics the rollout in flight last took into account
+	ObservedMetricsHash string `json:"observedMetricsHash,omitempty"`
+	// ObservedTemplateHash is the hash of the target's pod template the new version was created from
+	ObservedTemplateHash string `json:"observedTemplateHash,omitempty"`
+	// StartedAt is when the current rollout attempt started
+	StartedAt *metav1.Time `json:"startedAt,omitempty"`
+	// StepResults are the analysis results of the current rollout attempt
+	StepResults []StepResult `json:"stepResults,omitempty"`
+	// InconclusiveAnalyses counts the analyses of the current step in a row that were inconclusive
+	InconclusiveAnalyses int32 `json:"inconclusiveAnalyses,omitempty"`
+	// RollbackReason explains why the current rollout attempt is rolling back
+	RollbackReason string `json:"rollbackReason,omitempty"`
+	// RestartedAt is the spec.restartAt the controller last restarted the rollout for
+	RestartedAt *metav1.Time `json:"restartedAt,omitempty"`
+	// RestartRequest is the spec.restartRequest the controller last restarted the rollout for
+	RestartRequest int64 `json:"restartRequest,omitempty"`
+	// RolledBackTo is the spec.rollbackTo the controller last acted on, whether the
+	// revision was restored or not
+	RolledBackTo *RollbackConfig `json:"rolledBackTo,omitempty"`
+	// Plan is what the rollout would do, computed while spec.dryRun is set
+	Plan *RolloutPlan `json:"plan,omitempty"`
+	// History records finished rollout attempts, oldest first, bounded by spec.revisionHistoryLimit
+	History []RolloutRevision `json:"history,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:shortName=pd
+// +kubebuilder:printcolumn:name="Strategy",type=string,JSONPath=`.spec.strategy`,priority=1
 // +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
 // +kubebuilder:printcolumn:name="Step",type=integer,JSONPath=`.status.currentStep`
 // +kubebuilder:printcolumn:name="Canary%",type=integer,JSONPath=`.status.canaryPercentage`
+// +kubebuilder:printcolumn:name="Effective%",type=integer,JSONPath=`.status.effectivePercentage`,priority=1
 // +kubebuilder:printcolumn:name="Health",type=string,JSONPath=`.status.healthStatus`
 // +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+	"github.com/ghanatava/bg-switch/internal/analysis"
+	"github.com/ghanatava/bg-switch/internal/controller"
+)
+
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze",
+	Short: "Work with the metric analysis of progressive deployments",
+}
+
+var replayCmd = &cobra.Command{
+	Use:   "replay [deployment-name]",
+	Short: "Re-run the analysis of a past rollout with other thresholds",
+	Long: `Re-run the metric analysis of the recorded steps of a rollout locally, to see which steps would have
+passed with other thresholds. Steps are read from status.history, or from a JSON export of the
+progressive deployment (kubectl get pd NAME -o json) with --file. The recorded samples are used,
+unless --prometheus is set: the queries are then run again against that endpoint at the time each
+step was analyzed, with the credentials of the flags or of spec.metrics.authSecretRef, and each
+query is bounded by --timeout like the controller's analysis.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runReplay,
+}
+
+var (
+	replayFile               string
+	replayRevision           int64
+	replayPrometheus         string
+	replayBearerToken        string
+	replayUsername           string
+	replayPassword           string
+	replayTimeout            time.Duration
+	replayErrorRateThreshold float64
+	replayLatencyThreshold   float64
+)
+
+func init() {
+	replayCmd.Flags().StringVarP(&replayFile, "file", "f", "", "JSON export of the progressive deployment to read instead of the cluster")
+	replayCmd.Flags().Int64Var(&replayRevision, "revision", 0, "Revision to replay (defaults to the latest, or the rollout in progress without history)")
+	replayCmd.Flags().StringVar(&replayPrometheus, "prometheus", "", "Prometheus-compatible endpoint to query instead of using the recorded samples")
+	replayCmd.Flags().StringVar(&replayBearerToken, "bearer-token", "", "Bearer token for --prometheus (defaults to spec.metrics.authSecretRef)")
+	replayCmd.Flags().StringVar(&replayUsername, "username", "", "Basic auth username for --prometheus (defaults to spec.metrics.authSecretRef)")
+	replayCmd.Flags().StringVar(&replayPassword, "password", "", "Basic auth password for --prometheus")
+	replayCmd.Flags().DurationVar(&replayTimeout, "timeout", controller.DefaultAnalysisTimeout, "Time each --prometheus query may take before the step is inconclusive")
+	replayCmd.Flags().Float64Var(&replayErrorRateThreshold, "error-rate-threshold", 0, "Error rate threshold to replay with (defaults to spec.metrics)")
+	replayCmd.Flags().Float64Var(&replayLatencyThreshold, "latency-threshold", 0, "Latency threshold to replay with (defaults to spec.metrics)")
+	analyzeCmd.AddCommand(replayCmd)
+	rootCmd.AddCommand(analyzeCmd)
+}
+
+func runReplay(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if replayFile == "" && len(args) == 0 {
+		return fmt.Errorf("a deployment name or --file is required")
+	}
+	pd, err := loadProgressiveDeployment(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	// Pick the recorded steps to replay
+	label, steps, err := replaySteps(pd)
+	if err != nil {
+		return err
+	}
+
+	// Replay with the configured thresholds, overridden by the flags
+	metrics := pd.Spec.Metrics
+	if cmd.Flags().Changed("error-rate-threshold") {
+		metrics.ErrorRate.Threshold = replayErrorRateThreshold
+	}
+	if cmd.Flags().Changed("latency-threshold") {
+		metrics.Latency.Threshold = replayLatencyThreshold
+	}
+	checks := analysis.ChecksFor(metrics)
+	if len(checks) == 0 {
+		return fmt.Errorf("%s has no metrics configured", pd.Name)
+	}
+
+	var querier analysis.Querier
+	source := "recorded samples"
+	if replayPrometheus != "" {
+		endpoint, err := replayEndpoint(ctx, pd)
+		if err != nil {
+			return err
+		}
+		if querier, err = controller.NewMetricsClientCache(replayTimeout, 0).Get(endpoint); err != nil {
+			return err
+		}
+		source = replayPrometheus
+	}
+
+	thresholds := make([]string, 0, len(checks))
+	for _, check := range checks {
+		thresholds = append(thresholds, fmt.Sprintf("%s <= %g", check.Name, check.Threshold))
+	}
+	fmt.Printf("Replaying %s of %s with %s (%s)\n\n", label, pd.Name, strings.Join(thresholds, ", "), source)
+
+	// Print table
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	header := "STEP\tWEIGHT"
+	for _, check := range checks {
+		header += "\t" + strings.ToUpper(check.Name)
+	}
+	fmt.Fprintln(w, header+"\tRECORDED\tREPLAYED")
+
+	firstFailure := -1
+	for _, step := range steps {
+		var result analysis.Result
+		if querier != nil {
+			result = queryStep(ctx, querier, checks, step)
+		} else {
+			result = analysis.Evaluate(checks, step.Metrics)
+		}
+		if !result.Healthy && !result.Inconclusive && firstFailure < 0 {
+			firstFailure = step.Step
+		}
+
+		row := fmt.Sprintf("%d\t%d%%", step.Step, step.CanaryPercentage)
+		for _, check := range result.Checks {
+			switch {
+			case check.Inconclusive:
+				row += "\t- ⏳"
+			case !check.Measured:
+				row += "\t- ❌"
+			case check.Passed:
+				row += fmt.Sprintf("\t%.6f ✅", check.Value)
+			default:
+				row += fmt.Sprintf("\t%.6f ❌", check.Value)
+			}
+		}
+		replayed := passFail(result.Healthy)
+		if result.Inconclusive {
+			replayed = "inconclusive"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", row, passFail(step.Healthy), replayed)
+	}
+	w.Flush()
+
+	// Summarize what the rollout would have done
+	switch {
+	case firstFailure >= 0:
+		fmt.Printf("\n🔄 With these thresholds the rollout would have rolled back at step %d\n", firstFailure)
+	case len(steps) < specStepCount(pd):
+		fmt.Printf("\n✅ With these thresholds all %d recorded steps pass; the remaining steps never ran\n", len(steps))
+	default:
+		fmt.Printf("\n✅ With these thresholds every step passes\n")
+	}
+	return nil
+}
+
+// loadProgressiveDeployment reads the progressive deployment from --file or from the cluster
+func loadProgressiveDeployment(ctx context.Context, args []string) (*appsv1alpha1.ProgressiveDeployment, error) {
+	pd := &appsv1alpha1.ProgressiveDeployment{}
+	if replayFile != "" {
+		data, err := os.ReadFile(replayFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", replayFile, err)
+		}
+		if err := json.Unmarshal(data, pd); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", replayFile, err)
+		}
+		return pd, nil
+	}
+
+	// Get dynamic client
+	config, err := getKubeConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+
+	// Define the GVR
+	gvr := schema.GroupVersionResource{
+		Group:    "apps.my.domain",
+		Version:  "v1alpha1",
+		Resource: "progressivedeployments",
+	}
+
+	// Get the ProgressiveDeployment
+	obj, err := dynamicClient.Resource(gvr).Namespace(namespace).Get(ctx, args[0], metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get progressive deployment: %w", err)
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, pd); err != nil {
+		return nil, fmt.Errorf("failed to decode progressive deployment: %w", err)
+	}
+	return pd, nil
+}
+
+// replaySteps returns the recorded steps of --revision, of the latest revision, or of the
+// rollout in progress when there is no history
+func replaySteps(pd *appsv1alpha1.ProgressiveDeployment) (string, []appsv1alpha1.StepResult, error) {
+	history := pd.Status.History
+	if replayRevision != 0 {
+		for _, record := range history {
+			if record.Revision == replayRevision {
+				return fmt.Sprintf("revision %d (%s)", record.Revision, record.Phase), record.Steps, nil
+			}
+		}
+		return "", nil, fmt.Errorf("revision %d not found in history", replayRevision)
+	}
+	if len(history) > 0 {
+		record := history[len(history)-1]
+		return fmt.Sprintf("revision %d (%s)", record.Revision, record.Phase), record.Steps, nil
+	}
+	if len(pd.Status.StepResults) > 0 {
+		return "the current rollout", pd.Status.StepResults, nil
+	}
+	return "", nil, fmt.Errorf("no analyzed steps recorded for %s", pd.Name)
+}
+
+// queryStep analyzes the checks again at the time the step was analyzed, the way the
+// controller does. A query that fails leaves its check without a measurement
+func queryStep(ctx context.Context, querier analysis.Querier, checks []analysis.Check, step appsv1alpha1.StepResult) analysis.Result {
+	result := analysis.Analyze(ctx, querier, checks, step.AnalyzedAt.Time, analysis.Options{Timeout: replayTimeout})
+	for _, check := range result.Checks {
+		if check.Err != nil {
+			fmt.Fprintf(os.Stderr, "step %d: %v\n", step.Step, check.Err)
+		}
+	}
+	return result
+}
+
+// replayEndpoint returns the --prometheus endpoint with the credentials of the flags or,
+// when the progressive deployment is read from the cluster, of spec.metrics.authSecretRef
+func replayEndpoint(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (controller.MetricsEndpoint, error) {
+	endpoint := controller.MetricsEndpoint{
+		URL:         replayPrometheus,
+		BearerToken: replayBearerToken,
+		Username:    replayUsername,
+		Password:    replayPassword,
+	}
+	ref := pd.Spec.Metrics.AuthSecretRef
+	if endpoint.BearerToken != "" || endpoint.Username != "" || ref == nil || replayFile != "" {
+		return endpoint, nil
+	}
+
+	clientset, err := getKubeClient()
+	if err != nil {
+		return endpoint, err
+	}
+	secret, err := clientset.CoreV1().Secrets(pd.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return endpoint, fmt.Errorf("failed to get metrics auth secret %q: %w", ref.Name, err)
+	}
+	return endpoint.WithCredentials(secret)
+}
+
+// specStepCount returns the number of steps of a typed progressive deployment
+func specStepCount(pd *appsv1alpha1.ProgressiveDeployment) int {
+	spec, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&pd.Spec)
+	if err != nil {
+		return 0
+	}
+	return getStepCount(spec)
+}
+
+func passFail(passed bool) string {
+	if passed {
+		return "pass"
+	}
+	return "fail"
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history [deployment-name]",
+	Short: "List the rollout history of a progressive deployment",
+	Long:  `Display the finished rollouts of a progressive deployment, or the per-step analysis results of one revision.`,
+	Args:  cobra.ExactArgs(1),
+	RunE:  runHistory,
+}
+
+var (
+	historyRevision int64
+)
+
+func init() {
+	historyCmd.Flags().Int64Var(&historyRevision, "revision", 0, "Show the step results of this revision")
+	rootCmd.AddCommand(historyCmd)
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	deploymentName := args[0]
+
+	// Get dynamic client
+	config, err := getKubeConfig()
+	if err != nil {
+		return err
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+
+	// Define the GVR
+	gvr := schema.GroupVersionResource{
+		Group:    "apps.my.domain",
+		Version:  "v1alpha1",
+		Resource: "progressivedeployments",
+	}
+
+	ctx := context.Background()
+
+	// Get the ProgressiveDeployment
+	pd, err := dynamicClient.Resource(gvr).Namespace(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get progressive deployment: %w", err)
+	}
+
+	history, _, _ := unstructured.NestedSlice(pd.Object, "status", "history")
+	if len(history) == 0 {
+		fmt.Printf("No rollout history recorded for %s\n", deploymentName)
+		return nil
+	}
+
+	if historyRevision != 0 {
+		for _, item := range history {
+			record, ok := item.(map[string]interface{})
+			if ok && getInt64Field(record, "revision") == historyRevision {
+				displayRevision(record)
+				return nil
+			}
+		}
+		return fmt.Errorf("revision %d not found in history", historyRevision)
+	}
+
+	// Print table
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "REVISION\tPHASE\tIMAGE\tTEMPLATE\tSTEPS\tSTARTED\tFINISHED\tREASON")
+
+	for _, item := range history {
+		record, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		steps, _, _ := unstructured.NestedSlice(record, "steps")
+		passed := 0
+		for _, step := range steps {
+			if stepMap, ok := step.(map[string]interface{}); ok {
+				if healthy, _, _ := unstructured.NestedBool(stepMap, "healthy"); healthy {
+					passed++
+				}
+			}
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d/%d\t%s\t%s\t%s\n",
+			getInt64Field(record, "revision"),
+			getStringField(record, "phase"),
+			getStringField(record, "image"),
+			getStringField(record, "templateHash"),
+			passed, len(steps),
+			getStringField(record, "startedAt"),
+			getStringField(record, "finishedAt"),
+			getStringField(record, "rollbackReason"))
+	}
+
+	w.Flush()
+	return nil
+}
+
+func displayRevision(record map[string]interface{}) {
+	fmt.Printf("Revision:  %d\n", getInt64Field(record, "revision"))
+	fmt.Printf("Phase:     %s\n", getStringField(record, "phase"))
+	fmt.Printf("Image:     %s\n", getStringField(record, "image"))
+	fmt.Printf("Template:  %s\n", getStringField(record, "templateHash"))
+	fmt.Printf("Started:   %s\n", getStringField(record, "startedAt"))
+	fmt.Printf("Finished:  %s\n", getStringField(record, "finishedAt"))
+	if reason := getStringField(record, "rollbackReason"); reason != "" {
+		fmt.Printf("Reason:    %s\n", reason)
+	}
+
+	steps, _, _ := unstructured.NestedSlice(record, "steps")
+	if len(steps) == 0 {
+		return
+	}
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "STEP\tPERCENTAGE\tHEALTHY\tMETRICS\tANALYZED")
+	for _, step := range steps {
+		stepMap, ok := step.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		healthy, _, _ := unstructured.NestedBool(stepMap, "healthy")
+		metrics, _, _ := unstructured.NestedMap(stepMap, "metrics")
+
+		keys := make([]string, 0, len(metrics))
+		for key := range metrics {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		values := make([]string, 0, len(keys))
+		for _, key := range keys {
+			values = append(values, fmt.Sprintf("%s=%v", key, metrics[key]))
+		}
+		if message := getStringField(stepMap, "message"); message != "" {
+			values = append(values, message)
+		}
+
+		fmt.Fprintf(w, "%d\t%d%%\t%t\t%s\t%s\n",
+			getInt64Field(stepMap, "step"),
+			getInt64Field(stepMap, "canaryPercentage"),
+			healthy,
+			strings.Join(values, " "),
+			getStringField(stepMap, "analyzedAt"))
+	}
+	w.Flush()
+}
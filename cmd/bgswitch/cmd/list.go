@@ -89,8 +89,7 @@ func runList(cmd *cobra.Command, args []string) error {
 		canaryPercentage := getInt64Field(status, "canaryPercentage")
 		healthStatus := getStringField(status, "healthStatus")
 
-		canarySteps := getInt64Slice(spec, "canarySteps")
-		totalSteps := len(canarySteps)
+		totalSteps := getStepCount(spec)
 
 		age := item.GetCreationTimestamp().String()
 
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+var planCmd = &cobra.Command{
+	Use:   "plan [deployment-name]",
+	Short: "Show what a progressive deployment would do without touching anything",
+	Long: `Display the dry-run plan of a progressive deployment: the stable and canary replicas of each step
+and the current metric values against their thresholds. Use --enable to set spec.dryRun and --disable
+to clear it and let the rollout run.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPlan,
+}
+
+var (
+	enableDryRun  bool
+	disableDryRun bool
+)
+
+func init() {
+	planCmd.Flags().BoolVar(&enableDryRun, "enable", false, "Set spec.dryRun so the operator plans the rollout instead of running it")
+	planCmd.Flags().BoolVar(&disableDryRun, "disable", false, "Clear spec.dryRun so the operator runs the rollout")
+	planCmd.MarkFlagsMutuallyExclusive("enable", "disable")
+	rootCmd.AddCommand(planCmd)
+}
+
+func runPlan(cmd *cobra.Command, args []string) error {
+	deploymentName := args[0]
+
+	// Get dynamic client
+	config, err := getKubeConfig()
+	if err != nil {
+		return err
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+
+	// Define the GVR
+	gvr := schema.GroupVersionResource{
+		Group:    "apps.my.domain",
+		Version:  "v1alpha1",
+		Resource: "progressivedeployments",
+	}
+
+	ctx := context.Background()
+
+	// Get the ProgressiveDeployment
+	pd, err := dynamicClient.Resource(gvr).Namespace(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get progressive deployment: %w", err)
+	}
+
+	if enableDryRun || disableDryRun {
+		unstructured.SetNestedField(pd.Object, enableDryRun, "spec", "dryRun")
+		if _, err := dynamicClient.Resource(gvr).Namespace(namespace).Update(ctx, pd, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update progressive deployment: %w", err)
+		}
+		if enableDryRun {
+			fmt.Printf("📝 Dry run enabled for %s\n", deploymentName)
+			fmt.Println("   The operator will plan the rollout without touching anything, run this command again to see the plan")
+		} else {
+			fmt.Printf("🚀 Dry run disabled for %s\n", deploymentName)
+			fmt.Println("   The operator will run the rollout")
+		}
+		return nil
+	}
+
+	plan, found, _ := unstructured.NestedMap(pd.Object, "status", "plan")
+	if !found {
+		dryRun, _, _ := unstructured.NestedBool(pd.Object, "spec", "dryRun")
+		if dryRun {
+			fmt.Printf("The operator has not planned %s yet\n", deploymentName)
+		} else {
+			fmt.Printf("No plan for %s, run 'bgswitch plan %s --enable' first\n", deploymentName, deploymentName)
+		}
+		return nil
+	}
+
+	displayPlan(plan)
+	return nil
+}
+
+func displayPlan(plan map[string]interface{}) {
+	fmt.Printf("Planned:   %s\n", getStringField(plan, "plannedAt"))
+	fmt.Printf("Replicas:  %d\n\n", getInt64Field(plan, "replicas"))
+
+	// Print the replica split of each step
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "STEP\tWEIGHT\tSTABLE\tCANARY\tEFFECTIVE")
+	steps, _, _ := unstructured.NestedSlice(plan, "steps")
+	for _, item := range steps {
+		step, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(w, "%d\t%d%%\t%d\t%d\t%d%%\n",
+			getInt64Field(step, "step"),
+			getInt64Field(step, "weight"),
+			getInt64Field(step, "stableReplicas"),
+			getInt64Field(step, "canaryReplicas"),
+			getInt64Field(step, "effectivePercentage"))
+	}
+	w.Flush()
+
+	// Print the metric checks as they would be evaluated now
+	checks, _, _ := unstructured.NestedSlice(plan, "checks")
+	if len(checks) > 0 {
+		fmt.Println()
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "CHECK\tVALUE\tTHRESHOLD\tRESULT")
+		for _, item := range checks {
+			check, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			threshold, _ := getFloat64Field(check, "threshold")
+			value := "-"
+			if v, ok := getFloat64Field(check, "value"); ok {
+				value = fmt.Sprintf("%.6f", v)
+			}
+			result := "✅ pass"
+			if errMsg := getStringField(check, "error"); errMsg != "" {
+				result = "❌ " + errMsg
+			} else if passed, _, _ := unstructured.NestedBool(check, "passed"); !passed {
+				result = "❌ fail"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%.6f\t%s\n", getStringField(check, "name"), value, threshold, result)
+		}
+		w.Flush()
+	}
+
+	switch getStringField(plan, "verdict") {
+	case "Promote":
+		fmt.Println("\n✅ With the current metrics the analysis would promote")
+	case "RollBack":
+		fmt.Println("\n🔄 With the current metrics the analysis would roll back")
+	}
+}
@@ -59,7 +59,7 @@ func runPromote(cmd *cobra.Command, args []string) error {
 
 	// Get spec
 	spec, _, _ := unstructured.NestedMap(pd.Object, "spec")
-	canarySteps := getInt64Slice(spec, "canarySteps")
+	stepCount := getStepCount(spec)
 	autoPromote, _, _ := unstructured.NestedBool(spec, "autoPromote")
 
 	// Validation
@@ -72,8 +72,9 @@ func runPromote(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("cannot promote in phase '%s'. Must be in 'Analyzing' or 'Promoting' phase", phase)
 	}
 
-	if int(currentStep) >= len(canarySteps)-1 {
-		return fmt.Errorf("already at final step (%d/%d)", currentStep+1, len(canarySteps))
+	// The single blueGreen step is promoted by switching over
+	if !isBlueGreen(spec) && int(currentStep) >= stepCount-1 {
+		return fmt.Errorf("already at final step (%d/%d)", currentStep+1, stepCount)
 	}
 
 	// Manual promotion: Move to Promoting phase
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+var retryCmd = &cobra.Command{
+	Use:   "retry [deployment-name]",
+	Short: "Restart a finished rollout",
+	Long:  `Restart a Completed, RolledBack or Failed progressive deployment without recreating it, reusing the canary if it still exists.`,
+	Args:  cobra.ExactArgs(1),
+	RunE:  runRetry,
+}
+
+var (
+	fromStep int
+)
+
+func init() {
+	retryCmd.Flags().IntVar(&fromStep, "from-step", 0, "Step index (0-based) to restart the rollout at")
+	rootCmd.AddCommand(retryCmd)
+}
+
+func runRetry(cmd *cobra.Command, args []string) error {
+	deploymentName := args[0]
+
+	// Get dynamic client
+	config, err := getKubeConfig()
+	if err != nil {
+		return err
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+
+	// Define the GVR
+	gvr := schema.GroupVersionResource{
+		Group:    "apps.my.domain",
+		Version:  "v1alpha1",
+		Resource: "progressivedeployments",
+	}
+
+	ctx := context.Background()
+
+	// Get the ProgressiveDeployment
+	pd, err := dynamicClient.Resource(gvr).Namespace(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get progressive deployment: %w", err)
+	}
+
+	// Validation
+	status, _, _ := unstructured.NestedMap(pd.Object, "status")
+	phase := getStringField(status, "phase")
+	if phase != "Completed" && phase != "RolledBack" && phase != "Failed" {
+		return fmt.Errorf("cannot retry in phase '%s'. Only Completed, RolledBack or Failed rollouts can be retried", phase)
+	}
+
+	spec, _, _ := unstructured.NestedMap(pd.Object, "spec")
+	stepCount := getStepCount(spec)
+	if fromStep < 0 || (stepCount > 0 && fromStep >= stepCount) {
+		return fmt.Errorf("step %d is out of range (%d steps)", fromStep, stepCount)
+	}
+
+	// Request the restart, the operator acts on every increase of restartRequest
+	restartRequest := getInt64Field(spec, "restartRequest")
+	if observed := getInt64Field(status, "restartRequest"); observed > restartRequest {
+		restartRequest = observed
+	}
+	unstructured.SetNestedField(pd.Object, restartRequest+1, "spec", "restartRequest")
+	unstructured.SetNestedField(pd.Object, int64(fromStep), "spec", "restartFromStep")
+
+	_, err = dynamicClient.Resource(gvr).Namespace(namespace).Update(ctx, pd, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update progressive deployment: %w", err)
+	}
+
+	fmt.Printf("🔁 Retrying %s from step %d\n", deploymentName, fromStep)
+	fmt.Println("   The operator will reset the status and start the rollout again")
+
+	return nil
+}
@@ -23,7 +23,12 @@ var rootCmd = &cobra.Command{
 Examples:
   bgswitch status my-app
   bgswitch promote my-app
-  bgswitch rollback my-app`,
+  bgswitch rollback my-app
+  bgswitch retry my-app --from-step 1
+  bgswitch history my-app
+  bgswitch undo my-app --to-revision 2
+  bgswitch plan my-app --enable
+  bgswitch analyze replay my-app --error-rate-threshold 0.02`,
 }
 
 func Execute() {
@@ -93,6 +98,17 @@ func getInt64Field(m map[string]interface{}, key string) int64 {
 	return 0
 }
 
+// getFloat64Field returns a number field and whether it is set
+func getFloat64Field(m map[string]interface{}, key string) (float64, bool) {
+	switch val := m[key].(type) {
+	case float64:
+		return val, true
+	case int64:
+		return float64(val), true
+	}
+	return 0, false
+}
+
 func getInt64Slice(m map[string]interface{}, key string) []int64 {
 	if val, ok := m[key]; ok {
 		if slice, ok := val.([]interface{}); ok {
@@ -109,3 +125,20 @@ func getInt64Slice(m map[string]interface{}, key string) []int64 {
 	}
 	return nil
 }
+
+// isBlueGreen reports whether spec selects the blueGreen strategy
+func isBlueGreen(spec map[string]interface{}) bool {
+	return getStringField(spec, "strategy") == "blueGreen"
+}
+
+// getStepCount returns the number of steps of the rollout: the single switch-over of
+// blueGreen, or the steps in spec.steps or spec.canarySteps
+func getStepCount(spec map[string]interface{}) int {
+	if isBlueGreen(spec) {
+		return 1
+	}
+	if steps, ok := spec["steps"].([]interface{}); ok && len(steps) > 0 {
+		return len(steps)
+	}
+	return len(getInt64Slice(spec, "canarySteps"))
+}
@@ -65,12 +65,12 @@ func displayStatus(pd *unstructured.Unstructured) {
 	phase := getStringField(status, "phase")
 	currentStep := getInt64Field(status, "currentStep")
 	canaryPercentage := getInt64Field(status, "canaryPercentage")
+	effectivePercentage := getInt64Field(status, "effectivePercentage")
 	healthStatus := getStringField(status, "healthStatus")
 	canaryDeployment := getStringField(status, "canaryDeployment")
 
 	// Get canary steps from spec
-	canarySteps := getInt64Slice(spec, "canarySteps")
-	totalSteps := len(canarySteps)
+	totalSteps := getStepCount(spec)
 
 	// Get metrics if available
 	metrics, _, _ := unstructured.NestedMap(status, "metrics")
@@ -81,6 +81,10 @@ func displayStatus(pd *unstructured.Unstructured) {
 	fmt.Println("├─────────────────────────────────────────────────┤")
 	fmt.Printf("│  Phase:           %-29s │\n", phase)
 	fmt.Printf("│  Step:            %d/%d (%-2d%%)                    │\n", currentStep, totalSteps, canaryPercentage)
+	if phase == "Analyzing" && effectivePercentage != canaryPercentage {
+		// Rounding the replica split can give the canary more or less than its step weight
+		fmt.Printf("│  Effective:       %-29s │\n", fmt.Sprintf("%d%% of replicas", effectivePercentage))
+	}
 	fmt.Printf("│  Health:          %-29s │\n", healthStatus)
 
 	if canaryDeployment != "" {
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+var undoCmd = &cobra.Command{
+	Use:   "undo [deployment-name]",
+	Short: "Restart a finished rollout from a past revision",
+	Long:  `Restart the progressive deployment from step 0, rolling out the template recorded for a revision in its history.`,
+	Args:  cobra.ExactArgs(1),
+	RunE:  runUndo,
+}
+
+var (
+	toRevision int64
+)
+
+func init() {
+	undoCmd.Flags().Int64Var(&toRevision, "to-revision", 0, "Revision to roll out again (see 'bgswitch history')")
+	_ = undoCmd.MarkFlagRequired("to-revision")
+	rootCmd.AddCommand(undoCmd)
+}
+
+func runUndo(cmd *cobra.Command, args []string) error {
+	deploymentName := args[0]
+
+	// Get dynamic client
+	config, err := getKubeConfig()
+	if err != nil {
+		return err
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+
+	// Define the GVR
+	gvr := schema.GroupVersionResource{
+		Group:    "apps.my.domain",
+		Version:  "v1alpha1",
+		Resource: "progressivedeployments",
+	}
+
+	ctx := context.Background()
+
+	// Get the ProgressiveDeployment
+	pd, err := dynamicClient.Resource(gvr).Namespace(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get progressive deployment: %w", err)
+	}
+
+	// Validation
+	status, _, _ := unstructured.NestedMap(pd.Object, "status")
+	phase := getStringField(status, "phase")
+	if phase != "Completed" && phase != "RolledBack" && phase != "Failed" {
+		return fmt.Errorf("cannot undo in phase '%s'. Wait for the rollout to finish or roll it back first", phase)
+	}
+
+	found := false
+	history, _, _ := unstructured.NestedSlice(status, "history")
+	for _, item := range history {
+		if record, ok := item.(map[string]interface{}); ok && getInt64Field(record, "revision") == toRevision {
+			if getStringField(record, "templateHash") == "" {
+				return fmt.Errorf("revision %d has no recorded template", toRevision)
+			}
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("revision %d not found in history", toRevision)
+	}
+
+	// Request the restart, the operator records the request in status.rolledBackTo once it has acted on it.
+	// requestedAt tells this request apart from an earlier one for the same revision
+	rollbackTo := map[string]interface{}{
+		"revision":    toRevision,
+		"requestedAt": metav1.NowMicro().UTC().Format(metav1.RFC3339Micro),
+	}
+	if err := unstructured.SetNestedField(pd.Object, rollbackTo, "spec", "rollbackTo"); err != nil {
+		return fmt.Errorf("failed to set rollbackTo: %w", err)
+	}
+
+	_, err = dynamicClient.Resource(gvr).Namespace(namespace).Update(ctx, pd, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update progressive deployment: %w", err)
+	}
+
+	fmt.Printf("⏪ Restarting %s from revision %d\n", deploymentName, toRevision)
+	fmt.Println("   The operator will roll out the recorded template from step 0")
+
+	return nil
+}
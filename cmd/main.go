@@ -20,6 +20,10 @@ import (
 	"crypto/tls"
 	"flag"
 	"os"
+	"time"
+
+	// Embed the time zone database, spec.schedule.timeZone must resolve in minimal images
+	_ "time/tzdata"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -29,6 +33,7 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
@@ -37,6 +42,7 @@ import (
 
 	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
 	"github.com/ghanatava/bg-switch/internal/controller"
+	"github.com/ghanatava/bg-switch/internal/notify"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -61,6 +67,8 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var metricsQueryTimeout time.Duration
+	var maxConcurrentQueries int
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -79,6 +87,11 @@ func main() {
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.DurationVar(&metricsQueryTimeout, "metrics-query-timeout", controller.DefaultMetricsQueryTimeout,
+		"How long a single Prometheus query of a rollout analysis may take before the analysis is inconclusive. "+
+			"0 uses the default of 30s.")
+	flag.IntVar(&maxConcurrentQueries, "metrics-max-concurrent-queries", controller.DefaultMaxConcurrentQueries,
+		"The maximum number of queries in flight against each Prometheus endpoint. 0 disables the limit.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -178,9 +191,20 @@ func main() {
 		os.Exit(1)
 	}
 
+	notifications := notify.NewDispatcher(notify.DefaultQueueSize)
+	if err := mgr.Add(notifications); err != nil {
+		setupLog.Error(err, "unable to set up notifications")
+		os.Exit(1)
+	}
+
 	if err := (&controller.ProgressiveDeploymentReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:           client.WithFieldOwner(mgr.GetClient(), controller.FieldManager),
+		Scheme:           mgr.GetScheme(),
+		APIReader:        mgr.GetAPIReader(),
+		Notifications:    notifications,
+		Recorder:         mgr.GetEventRecorderFor("progressivedeployment-controller"),
+		NewMetricsClient: controller.NewMetricsClientCache(metricsQueryTimeout, maxConcurrentQueries).Get,
+		AnalysisTimeout:  metricsQueryTimeout,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ProgressiveDeployment")
 		os.Exit(1)
@@ -0,0 +1,230 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package analysis decides whether a canary is healthy from measured metric values. Metrics
+// are only reached through a Querier, so the controller and the bgswitch CLI reach the same
+// decision from the same values and tests need no metrics backend
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"golang.org/x/sync/errgroup"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+// Names of the metrics, as keys of the measurements and of status.metrics
+const (
+	ErrorRate = "errorRate"
+	Latency   = "latency"
+)
+
+// Check compares a metric against the maximum acceptable value
+type Check struct {
+	// Name keys the check's measurement
+	Name string
+	// Query is the PromQL query measuring the metric
+	Query string
+	// Threshold is the maximum acceptable value
+	Threshold float64
+}
+
+// CheckResult is the outcome of one check
+type CheckResult struct {
+	Check
+	// Value is the measurement, zero when Measured is false
+	Value float64
+	// Measured reports whether there was a measurement for the check
+	Measured bool
+	// Passed reports whether the measurement is within the threshold
+	Passed bool
+	// Err is why the query of the check failed, nil when Measured
+	Err error
+	// Inconclusive reports whether the query did not complete in time
+	Inconclusive bool
+}
+
+// Result is the outcome of an analysis
+type Result struct {
+	// Healthy reports whether every check passed
+	Healthy bool
+	// Inconclusive reports whether the analysis cannot decide: no check failed on a
+	// measurement or a query error, but at least one query did not complete in time
+	Inconclusive bool
+	// Checks are the results of the checks, in the order they were given
+	Checks []CheckResult
+}
+
+// Failed returns the names of the checks that did not pass
+func (r Result) Failed() []string {
+	var failed []string
+	for _, check := range r.Checks {
+		if !check.Passed {
+			failed = append(failed, check.Name)
+		}
+	}
+	return failed
+}
+
+// Err joins the errors of the checks whose query failed, in check order
+func (r Result) Err() error {
+	var errs []error
+	for _, check := range r.Checks {
+		if check.Err != nil {
+			errs = append(errs, check.Err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Querier runs a metric query evaluated at a point in time. It is the only way the
+// analysis reaches a metrics backend, so it can be replaced by a fake in tests
+type Querier interface {
+	Query(ctx context.Context, query string, at time.Time) (float64, error)
+}
+
+// Options bound the queries of an analysis
+type Options struct {
+	// Concurrency is the number of queries in flight at once, zero runs every query at once
+	Concurrency int
+	// Timeout bounds each query, zero leaves it to the context
+	Timeout time.Duration
+}
+
+// Analyze runs the query of every check at the given time and evaluates the measurements.
+// The queries run concurrently, each with its own timeout, and the results keep the order
+// of the checks. A measured check over its threshold or a failed query makes the result
+// unhealthy; otherwise a query that did not complete in time makes it inconclusive
+func Analyze(ctx context.Context, querier Querier, checks []Check, at time.Time, opts Options) Result {
+	values := make([]float64, len(checks))
+	errs := make([]error, len(checks))
+	inconclusive := make([]bool, len(checks))
+
+	group := errgroup.Group{}
+	if opts.Concurrency > 0 {
+		group.SetLimit(opts.Concurrency)
+	}
+	for i, check := range checks {
+		group.Go(func() error {
+			values[i], inconclusive[i], errs[i] = measure(ctx, querier, check, at, opts.Timeout)
+			// Failures are kept per check, one check never cancels the others
+			return nil
+		})
+	}
+	_ = group.Wait()
+
+	measurements := make(map[string]float64, len(checks))
+	for i, check := range checks {
+		if errs[i] == nil {
+			measurements[check.Name] = values[i]
+		}
+	}
+	result := Evaluate(checks, measurements)
+	for i := range result.Checks {
+		result.Checks[i].Err = errs[i]
+		result.Checks[i].Inconclusive = inconclusive[i]
+	}
+
+	// A failure is decisive, timeouts only matter when nothing failed
+	if !result.Healthy {
+		failed := false
+		for _, check := range result.Checks {
+			failed = failed || (!check.Passed && !check.Inconclusive)
+		}
+		result.Inconclusive = !failed
+	}
+	return result
+}
+
+// measure runs the query of a check and reports whether it did not complete in time, either
+// within the timeout or within a deadline of the querier's own. It returns at the timeout
+// even if the querier ignores the context
+func measure(ctx context.Context, querier Querier, check Check, at time.Time, timeout time.Duration) (float64, bool, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	type outcome struct {
+		value float64
+		err   error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		value, err := querier.Query(ctx, check.Query, at)
+		done <- outcome{value: value, err: err}
+	}()
+
+	select {
+	case result := <-done:
+		if result.err == nil {
+			return result.value, false, nil
+		}
+		if ctx.Err() != nil || errors.Is(result.err, context.DeadlineExceeded) {
+			return 0, true, fmt.Errorf("%s query did not complete: %w", check.Name, result.err)
+		}
+		return 0, false, fmt.Errorf("%s query failed: %w", check.Name, result.err)
+	case <-ctx.Done():
+		return 0, true, fmt.Errorf("%s query did not complete: %w", check.Name, ctx.Err())
+	}
+}
+
+// ChecksFor returns the checks configured in spec.metrics, in the order they are evaluated
+func ChecksFor(metrics appsv1alpha1.MetricsConfig) []Check {
+	var checks []Check
+	if metrics.ErrorRate.Query != "" {
+		checks = append(checks, Check{Name: ErrorRate, Query: metrics.ErrorRate.Query, Threshold: metrics.ErrorRate.Threshold})
+	}
+	if metrics.Latency.Query != "" {
+		checks = append(checks, Check{Name: Latency, Query: metrics.Latency.Query, Threshold: metrics.Latency.Threshold})
+	}
+	return checks
+}
+
+// Evaluate compares the measurements, keyed by check name, with the thresholds of the checks.
+// A check without a measurement fails. Without checks the canary is healthy
+func Evaluate(checks []Check, measurements map[string]float64) Result {
+	result := Result{Healthy: true, Checks: make([]CheckResult, 0, len(checks))}
+	for _, check := range checks {
+		value, measured := measurements[check.Name]
+		passed := measured && value <= check.Threshold
+		result.Checks = append(result.Checks, CheckResult{Check: check, Value: value, Measured: measured, Passed: passed})
+		result.Healthy = result.Healthy && passed
+	}
+	return result
+}
+
+// SampleValue turns the result of a Prometheus instant query into a measurement:
+// the value of a scalar, or of the first sample of a vector
+func SampleValue(value model.Value) (float64, error) {
+	switch v := value.(type) {
+	case model.Vector:
+		if len(v) == 0 {
+			return 0, fmt.Errorf("no data returned from query")
+		}
+		return float64(v[0].Value), nil
+	case *model.Scalar:
+		return float64(v.Value), nil
+	default:
+		return 0, fmt.Errorf("unexpected result type: %T", value)
+	}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/common/model"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+	"github.com/ghanatava/bg-switch/internal/analysis/fake"
+)
+
+var _ = Describe("Analysis", func() {
+	checks := []Check{
+		{Name: ErrorRate, Query: "errors", Threshold: 0.05},
+		{Name: Latency, Query: "latency", Threshold: 0.5},
+	}
+
+	It("should check the configured metrics in order", func() {
+		metrics := appsv1alpha1.MetricsConfig{
+			ErrorRate: appsv1alpha1.MetricThreshold{Query: "errors", Threshold: 0.05},
+			Latency:   appsv1alpha1.MetricThreshold{Query: "latency", Threshold: 0.5},
+		}
+		Expect(ChecksFor(metrics)).To(Equal(checks))
+		Expect(ChecksFor(appsv1alpha1.MetricsConfig{Latency: metrics.Latency})).To(Equal(checks[1:]))
+		Expect(ChecksFor(appsv1alpha1.MetricsConfig{})).To(BeEmpty())
+	})
+
+	DescribeTable("evaluating measurements",
+		func(checks []Check, measurements map[string]float64, healthy bool, failed []string) {
+			result := Evaluate(checks, measurements)
+			Expect(result.Healthy).To(Equal(healthy))
+			Expect(result.Failed()).To(Equal(failed))
+			Expect(result.Checks).To(HaveLen(len(checks)))
+		},
+		Entry("is healthy without checks", nil, nil, true, nil),
+		Entry("is healthy when every metric is within its threshold",
+			checks, map[string]float64{ErrorRate: 0.01, Latency: 0.3}, true, nil),
+		Entry("accepts a value equal to the threshold",
+			checks, map[string]float64{ErrorRate: 0.05, Latency: 0.5}, true, nil),
+		Entry("fails a metric above its threshold",
+			checks, map[string]float64{ErrorRate: 0.07, Latency: 0.3}, false, []string{ErrorRate}),
+		Entry("fails every metric above its threshold",
+			checks, map[string]float64{ErrorRate: 0.07, Latency: 0.9}, false, []string{ErrorRate, Latency}),
+		Entry("fails a metric without a measurement",
+			checks, map[string]float64{ErrorRate: 0.01}, false, []string{Latency}),
+	)
+
+	DescribeTable("analyzing with queries",
+		func(querier *fake.Querier, healthy, inconclusive bool, measurements map[string]float64, failure string) {
+			result := Analyze(context.Background(), querier, checks, time.Now(), Options{Concurrency: 2, Timeout: 50 * time.Millisecond})
+			Expect(result.Healthy).To(Equal(healthy))
+			Expect(result.Inconclusive).To(Equal(inconclusive))
+			Expect(result.Checks).To(HaveLen(2))
+			Expect(result.Checks[0].Name).To(Equal(ErrorRate))
+			Expect(result.Checks[1].Name).To(Equal(Latency))
+			for _, check := range result.Checks {
+				if value, ok := measurements[check.Name]; ok {
+					Expect(check.Measured).To(BeTrue())
+					Expect(check.Value).To(Equal(value))
+				} else {
+					Expect(check.Measured).To(BeFalse())
+				}
+			}
+			if failure != "" {
+				Expect(result.Err()).To(MatchError(ContainSubstring(failure)))
+			} else {
+				Expect(result.Err()).NotTo(HaveOccurred())
+			}
+			Expect(querier.Queries()).To(ConsistOf("errors", "latency"))
+		},
+		Entry("is healthy when every metric is within its threshold",
+			&fake.Querier{Values: map[string]float64{"errors": 0.01, "latency": 0.3}},
+			true, false, map[string]float64{ErrorRate: 0.01, Latency: 0.3}, ""),
+		Entry("is unhealthy when a metric exceeds its threshold",
+			&fake.Querier{Values: map[string]float64{"errors": 0.07, "latency": 0.3}},
+			false, false, map[string]float64{ErrorRate: 0.07, Latency: 0.3}, ""),
+		Entry("runs every query even when one fails",
+			&fake.Querier{Errors: map[string]error{"errors": errors.New("connection refused")}, Values: map[string]float64{"latency": 0.3}},
+			false, false, map[string]float64{Latency: 0.3}, "errorRate query failed: connection refused"),
+		Entry("fails a query without data",
+			&fake.Querier{Values: map[string]float64{"errors": 0.01}},
+			false, false, map[string]float64{ErrorRate: 0.01}, "latency query failed: no data"),
+		Entry("is inconclusive when a query does not complete in time",
+			&fake.Querier{Values: map[string]float64{"errors": 0.01, "latency": 0.3}, Delays: map[string]time.Duration{"latency": time.Minute}},
+			false, true, map[string]float64{ErrorRate: 0.01}, "latency query did not complete"),
+		Entry("is inconclusive when the querier gives up on its own deadline",
+			&fake.Querier{Errors: map[string]error{"latency": fmt.Errorf("waiting for a free query slot: %w", context.DeadlineExceeded)}, Values: map[string]float64{"errors": 0.01}},
+			false, true, map[string]float64{ErrorRate: 0.01}, "latency query did not complete"),
+		Entry("is unhealthy when a metric exceeds its threshold while another query is slow",
+			&fake.Querier{Values: map[string]float64{"errors": 0.07, "latency": 0.3}, Delays: map[string]time.Duration{"latency": time.Minute}},
+			false, false, map[string]float64{ErrorRate: 0.07}, "latency query did not complete"),
+	)
+
+	It("should run the queries concurrently", func() {
+		querier := &fake.Querier{
+			Values: map[string]float64{"errors": 0.01, "latency": 0.3},
+			Delays: map[string]time.Duration{"errors": 200 * time.Millisecond, "latency": 200 * time.Millisecond},
+		}
+		start := time.Now()
+		result := Analyze(context.Background(), querier, checks, time.Now(), Options{Timeout: time.Second})
+		Expect(result.Healthy).To(BeTrue())
+		Expect(time.Since(start)).To(BeNumerically("<", 400*time.Millisecond))
+	})
+
+	It("should not wait for a querier that ignores its context", func() {
+		release := make(chan struct{})
+		defer close(release)
+		start := time.Now()
+		result := Analyze(context.Background(), blockingQuerier(release), checks, time.Now(), Options{Timeout: 50 * time.Millisecond})
+		Expect(result.Inconclusive).To(BeTrue())
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+	})
+
+	DescribeTable("reading the value of a query result",
+		func(value model.Value, expected float64, fails bool) {
+			sample, err := SampleValue(value)
+			if fails {
+				Expect(err).To(HaveOccurred())
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sample).To(Equal(expected))
+		},
+		Entry("takes the first sample of a vector",
+			model.Vector{{Value: 0.02}, {Value: 0.9}}, 0.02, false),
+		Entry("takes a scalar", &model.Scalar{Value: 0.3}, 0.3, false),
+		Entry("fails on an empty vector", model.Vector{}, 0.0, true),
+		Entry("fails on a matrix", model.Matrix{}, 0.0, true),
+	)
+})
+
+// blockingQuerier answers only once release is closed, whatever its context
+type blockingQuerier chan struct{}
+
+func (q blockingQuerier) Query(context.Context, string, time.Time) (float64, error) {
+	<-q
+	return 0, nil
+}
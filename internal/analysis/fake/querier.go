@@ -0,0 +1,69 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fake provides a Querier returning canned values, for testing the analysis
+// without a metrics backend
+package fake
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Querier returns the value or error configured for each query and records the queries it ran
+type Querier struct {
+	// Values are the results of the queries
+	Values map[string]float64
+	// Errors fail the queries they are keyed by
+	Errors map[string]error
+	// Delays hold the queries they are keyed by, until the delay passes or the context is done
+	Delays map[string]time.Duration
+
+	mu      sync.Mutex
+	queries []string
+}
+
+// Query returns the configured error or value of query. A query without either has no data
+func (q *Querier) Query(ctx context.Context, query string, _ time.Time) (float64, error) {
+	q.mu.Lock()
+	q.queries = append(q.queries, query)
+	q.mu.Unlock()
+
+	if delay, ok := q.Delays[query]; ok {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+
+	if err, ok := q.Errors[query]; ok {
+		return 0, err
+	}
+	if value, ok := q.Values[query]; ok {
+		return value, nil
+	}
+	return 0, fmt.Errorf("no data returned from query")
+}
+
+// Queries returns the queries run so far, in the order they started
+func (q *Querier) Queries() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]string(nil), q.queries...)
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+// FieldManager is the field manager the controller writes with, so the fields it owns
+// are told apart from those of kubectl, autoscalers and GitOps tools
+const FieldManager = "bgswitch-controller"
+
+// scaleWorkload sets the replicas of a workload with server-side apply. The controller
+// only owns spec.replicas, so concurrent changes to other fields are neither overwritten
+// nor cause conflicts
+func (r *ProgressiveDeploymentReconciler) scaleWorkload(ctx context.Context, workload Workload, replicas int32) error {
+	obj := workload.Object()
+	gvk, err := apiutil.GVKForObject(obj, r.Scheme)
+	if err != nil {
+		return err
+	}
+
+	apply := &unstructured.Unstructured{}
+	apply.SetGroupVersionKind(gvk)
+	apply.SetNamespace(obj.GetNamespace())
+	apply.SetName(obj.GetName())
+	if err := unstructured.SetNestedField(apply.Object, int64(replicas), "spec", "replicas"); err != nil {
+		return err
+	}
+
+	// Take spec.replicas over from whoever scaled the workload last
+	if err := r.Apply(ctx, client.ApplyConfigurationFromUnstructured(apply),
+		client.FieldOwner(FieldManager), client.ForceOwnership); err != nil {
+		return err
+	}
+	workload.SetReplicas(replicas)
+	return nil
+}
+
+// statusReadKey is the context key of the status a reconcile read
+type statusReadKey struct{}
+
+// statusRead is the status of the ProgressiveDeployment a reconcile read, kept up to date
+// with the reconcile's own status writes
+type statusRead struct {
+	key    client.ObjectKey
+	status appsv1alpha1.ProgressiveDeploymentStatus
+}
+
+// withStatusRead remembers the status of pd as the reconcile read it, so that updateStatus
+// can tell a status written by someone else from the reconcile's own
+func withStatusRead(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) context.Context {
+	return context.WithValue(ctx, statusReadKey{}, &statusRead{
+		key:    client.ObjectKeyFromObject(pd),
+		status: *pd.Status.DeepCopy(),
+	})
+}
+
+// statusReadFrom returns the status the reconcile read pd with, nil when it is unknown
+func statusReadFrom(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) *statusRead {
+	read, ok := ctx.Value(statusReadKey{}).(*statusRead)
+	if !ok || read.key != client.ObjectKeyFromObject(pd) {
+		return nil
+	}
+	return read
+}
+
+// apiReader reads around the cache, falling back to the client
+func (r *ProgressiveDeploymentReconciler) apiReader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+	return r.Client
+}
+
+// updateStatus patches the ProgressiveDeployment status onto the resource version the
+// reconcile has. A conflict is retried on the latest version read around the cache as long
+// as only metadata or spec changed in between. A status someone else wrote in the meantime,
+// for example bgswitch setting status.phase, is kept and the conflict returned, so the
+// reconcile starts over from the latest version
+func (r *ProgressiveDeploymentReconciler) updateStatus(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) error {
+	key := client.ObjectKeyFromObject(pd)
+	read := statusReadFrom(ctx, pd)
+
+	// The patch is computed against the status the reconcile read
+	stored := pd.DeepCopy()
+	if read != nil {
+		stored.Status = *read.status.DeepCopy()
+	} else {
+		if err := r.apiReader().Get(ctx, key, stored); err != nil {
+			return err
+		}
+		if stored.ResourceVersion != pd.ResourceVersion {
+			return errors.NewConflict(appsv1alpha1.GroupVersion.WithResource("progressivedeployments").GroupResource(),
+				pd.Name, fmt.Errorf("the object has been modified since it was read"))
+		}
+	}
+
+	status := pd.Status.DeepCopy()
+	statusChanged := false
+	return retry.OnError(retry.DefaultRetry, func(err error) bool {
+		return errors.IsConflict(err) && !statusChanged
+	}, func() error {
+		updated := stored.DeepCopy()
+		updated.Status = *status.DeepCopy()
+		patch := client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})
+		err := r.Status().Patch(ctx, updated, patch, client.FieldOwner(FieldManager))
+		if err == nil {
+			pd.ResourceVersion = updated.ResourceVersion
+			if read != nil {
+				read.status = *updated.Status.DeepCopy()
+			}
+			return nil
+		}
+		if !errors.IsConflict(err) || read == nil {
+			statusChanged = true
+			return err
+		}
+
+		latest := &appsv1alpha1.ProgressiveDeployment{}
+		if getErr := r.apiReader().Get(ctx, key, latest); getErr != nil {
+			return getErr
+		}
+		if !equality.Semantic.DeepEqual(latest.Status, read.status) {
+			statusChanged = true
+			return err
+		}
+		stored = latest
+		return err
+	})
+}
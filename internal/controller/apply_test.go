@@ -0,0 +1,239 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+var _ = Describe("Conflict-safe writes", func() {
+	ctx := context.Background()
+	var reconciler *ProgressiveDeploymentReconciler
+
+	BeforeEach(func() {
+		reconciler = &ProgressiveDeploymentReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+	})
+
+	Context("When scaling a workload", func() {
+		var target *appsv1.Deployment
+
+		BeforeEach(func() {
+			labels := map[string]string{"app": "api"}
+			target = &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: ptr.To[int32](3),
+					Selector: &metav1.LabelSelector{MatchLabels: labels},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: labels},
+						Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "api", Image: "api:1.0"}}},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, target, client.FieldOwner("kubectl-client-side-apply"))).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.Delete(ctx, target)).To(Succeed())
+		})
+
+		It("should own only spec.replicas and keep fields changed by other managers", func() {
+			workload, err := reconciler.getWorkload(ctx, &appsv1alpha1.ProgressiveDeployment{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default"},
+				Spec:       appsv1alpha1.ProgressiveDeploymentSpec{TargetDeployment: "api"},
+			}, "api")
+			Expect(err).NotTo(HaveOccurred())
+
+			// A GitOps sync changes the image after the controller read the Deployment
+			synced := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(target), synced)).To(Succeed())
+			synced.Spec.Template.Spec.Containers[0].Image = "api:2.0"
+			synced.Annotations = map[string]string{"argocd.argoproj.io/sync-wave": "1"}
+			Expect(k8sClient.Update(ctx, synced, client.FieldOwner("argocd-controller"))).To(Succeed())
+
+			Expect(reconciler.scaleWorkload(ctx, workload, 1)).To(Succeed())
+			Expect(workload.Replicas()).To(Equal(int32(1)))
+
+			stored := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(target), stored)).To(Succeed())
+			Expect(*stored.Spec.Replicas).To(Equal(int32(1)))
+			Expect(stored.Spec.Template.Spec.Containers[0].Image).To(Equal("api:2.0"))
+			Expect(stored.Annotations).To(HaveKeyWithValue("argocd.argoproj.io/sync-wave", "1"))
+
+			managers := map[string]metav1.ManagedFieldsOperationType{}
+			for _, entry := range stored.ManagedFields {
+				managers[entry.Manager] = entry.Operation
+			}
+			Expect(managers).To(HaveKeyWithValue(FieldManager, metav1.ManagedFieldsOperationApply))
+			Expect(managers).To(HaveKey("argocd-controller"))
+		})
+	})
+
+	Context("When writing the status", func() {
+		var pd *appsv1alpha1.ProgressiveDeployment
+
+		BeforeEach(func() {
+			pd = &appsv1alpha1.ProgressiveDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "status-test", Namespace: "default"},
+				Spec: appsv1alpha1.ProgressiveDeploymentSpec{
+					TargetDeployment: "api",
+					CanarySteps:      []int{50, 100},
+				},
+			}
+			Expect(k8sClient.Create(ctx, pd)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.Delete(ctx, pd)).To(Succeed())
+		})
+
+		It("should write the status twice in one reconcile through a cached client", func() {
+			cached := &staleCache{Client: k8sClient, snapshot: pd.DeepCopy()}
+			reconciler = &ProgressiveDeploymentReconciler{Client: cached, APIReader: k8sClient, Scheme: k8sClient.Scheme()}
+
+			read := &appsv1alpha1.ProgressiveDeployment{}
+			Expect(cached.Get(ctx, client.ObjectKeyFromObject(pd), read)).To(Succeed())
+			reconcileCtx := withStatusRead(ctx, read)
+
+			// The cache does not see the first write before the second one
+			read.Status.Phase = "Analyzing"
+			Expect(reconciler.updateStatus(reconcileCtx, read)).To(Succeed())
+			read.Status.CanaryPercentage = 50
+			Expect(reconciler.updateStatus(reconcileCtx, read)).To(Succeed())
+
+			stored := &appsv1alpha1.ProgressiveDeployment{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pd), stored)).To(Succeed())
+			Expect(stored.Status.Phase).To(Equal("Analyzing"))
+			Expect(stored.Status.CanaryPercentage).To(Equal(50))
+		})
+
+		It("should retry a conflict when only the metadata changed in the meantime", func() {
+			read := pd.DeepCopy()
+			reconcileCtx := withStatusRead(ctx, read)
+
+			labeled := &appsv1alpha1.ProgressiveDeployment{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pd), labeled)).To(Succeed())
+			labeled.Labels = map[string]string{"team": "payments"}
+			Expect(k8sClient.Update(ctx, labeled)).To(Succeed())
+
+			read.Status.Phase = "Analyzing"
+			Expect(reconciler.updateStatus(reconcileCtx, read)).To(Succeed())
+
+			stored := &appsv1alpha1.ProgressiveDeployment{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pd), stored)).To(Succeed())
+			Expect(stored.Status.Phase).To(Equal("Analyzing"))
+			Expect(stored.Labels).To(HaveKeyWithValue("team", "payments"))
+			Expect(read.ResourceVersion).To(Equal(stored.ResourceVersion))
+		})
+
+		It("should not revert a status.phase changed in the meantime", func() {
+			stale := pd.DeepCopy()
+			reconcileCtx := withStatusRead(ctx, stale)
+
+			// bgswitch rollback sets the phase after the controller read the object
+			rolledBack := &appsv1alpha1.ProgressiveDeployment{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pd), rolledBack)).To(Succeed())
+			rolledBack.Status.Phase = "RollingBack"
+			Expect(k8sClient.Status().Update(ctx, rolledBack)).To(Succeed())
+
+			stale.Status.Phase = "Analyzing"
+			stale.Status.CanaryPercentage = 50
+			err := reconciler.updateStatus(reconcileCtx, stale)
+			Expect(errors.IsConflict(err)).To(BeTrue())
+
+			stored := &appsv1alpha1.ProgressiveDeployment{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pd), stored)).To(Succeed())
+			Expect(stored.Status.Phase).To(Equal("RollingBack"))
+			Expect(stored.Status.CanaryPercentage).To(BeZero())
+		})
+
+		It("should patch the status once the reconcile read the latest version", func() {
+			stale := pd.DeepCopy()
+
+			// Someone labels the object after the controller read it
+			labeled := &appsv1alpha1.ProgressiveDeployment{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pd), labeled)).To(Succeed())
+			labeled.Labels = map[string]string{"team": "payments"}
+			Expect(k8sClient.Update(ctx, labeled)).To(Succeed())
+
+			stale.Status.Phase = "Analyzing"
+			Expect(errors.IsConflict(reconciler.updateStatus(ctx, stale))).To(BeTrue())
+
+			// The retried reconcile starts from the latest version
+			latest := &appsv1alpha1.ProgressiveDeployment{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pd), latest)).To(Succeed())
+			latest.Status.Phase = "Analyzing"
+			latest.Status.CanaryPercentage = 50
+			Expect(reconciler.updateStatus(ctx, latest)).To(Succeed())
+
+			stored := &appsv1alpha1.ProgressiveDeployment{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pd), stored)).To(Succeed())
+			Expect(stored.Status.Phase).To(Equal("Analyzing"))
+			Expect(stored.Status.CanaryPercentage).To(Equal(50))
+			Expect(stored.Labels).To(HaveKeyWithValue("team", "payments"))
+
+			// Fields cleared in memory are cleared in the stored status as well
+			latest.Status.CanaryPercentage = 0
+			Expect(reconciler.updateStatus(ctx, latest)).To(Succeed())
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pd), stored)).To(Succeed())
+			Expect(stored.Status.CanaryPercentage).To(BeZero())
+		})
+	})
+})
+
+// staleCache serves a ProgressiveDeployment the way a cache does that has not seen the
+// controller's own writes yet
+type staleCache struct {
+	client.Client
+	snapshot *appsv1alpha1.ProgressiveDeployment
+}
+
+func (c *staleCache) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if pd, ok := obj.(*appsv1alpha1.ProgressiveDeployment); ok && key == client.ObjectKeyFromObject(c.snapshot) {
+		c.snapshot.DeepCopyInto(pd)
+		return nil
+	}
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+// racingWriter runs write once before the first patch, the way another writer changes an
+// object between the controller's read and its patch, and counts the patches
+type racingWriter struct {
+	client.Client
+	write   func()
+	patches int
+}
+
+func (c *racingWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if write := c.write; write != nil {
+		c.write = nil
+		write()
+	}
+	c.patches++
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
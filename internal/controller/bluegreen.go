@@ -0,0 +1,293 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+// previewServiceName returns the name of the preview Service for a blueGreen rollout
+func previewServiceName(pd *appsv1alpha1.ProgressiveDeployment) string {
+	if pd.Spec.BlueGreen.PreviewService != "" {
+		return pd.Spec.BlueGreen.PreviewService
+	}
+	return fmt.Sprintf("%s-preview", pd.Spec.BlueGreen.ActiveService)
+}
+
+// greenSelector returns the Service selector that matches only the green pods
+func greenSelector(activeSelector map[string]string, trackKey string) map[string]string {
+	selector := make(map[string]string, len(activeSelector)+1)
+	for k, v := range activeSelector {
+		if k == podTemplateHashLabel || k == controllerRevisionHashLabel {
+			continue
+		}
+		selector[k] = v
+	}
+	selector[trackKey] = "green"
+	return selector
+}
+
+// getService fetches a Service in the ProgressiveDeployment namespace
+func (r *ProgressiveDeploymentReconciler) getService(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, name string) (*corev1.Service, error) {
+	service := &corev1.Service{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: pd.Namespace, Name: name}, service); err != nil {
+		return nil, err
+	}
+	return service, nil
+}
+
+// setServiceSelector points a Service at the pods matching selector
+func (r *ProgressiveDeploymentReconciler) setServiceSelector(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, name string, selector map[string]string) error {
+	service, err := r.getService(ctx, pd, name)
+	if err != nil {
+		return err
+	}
+	if maps.Equal(service.Spec.Selector, selector) {
+		return nil
+	}
+	// Patch only the selector, other fields of the Service may be managed elsewhere
+	patch := client.MergeFrom(service.DeepCopy())
+	service.Spec.Selector = selector
+	return r.Patch(ctx, service, patch)
+}
+
+// ensurePreviewService creates the preview Service from the active one, or repoints it at green
+func (r *ProgressiveDeploymentReconciler) ensurePreviewService(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, active *corev1.Service, selector map[string]string) error {
+	log := logf.FromContext(ctx)
+	name := previewServiceName(pd)
+
+	err := r.setServiceSelector(ctx, pd, name, selector)
+	if err == nil || !errors.IsNotFound(err) {
+		return err
+	}
+
+	preview := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: pd.Namespace,
+			Labels: map[string]string{
+				"progressive-deployment": pd.Name,
+				"deployment-type":        "preview",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: selector,
+			Type:     corev1.ServiceTypeClusterIP,
+		},
+	}
+	for _, port := range active.Spec.Ports {
+		port.NodePort = 0
+		preview.Spec.Ports = append(preview.Spec.Ports, port)
+	}
+
+	if err := ctrl.SetControllerReference(pd, preview, r.Scheme); err != nil {
+		return err
+	}
+	if err := r.Create(ctx, preview); err != nil {
+		return err
+	}
+
+	log.Info("Created preview service", "name", name)
+	return nil
+}
+
+// blueGreenStrategy runs a full-size green workload next to blue and switches the active Service at once
+type blueGreenStrategy struct {
+	*ProgressiveDeploymentReconciler
+}
+
+// Steps returns a single step: green is analyzed through the preview Service with no live traffic
+func (s *blueGreenStrategy) Steps(_ *appsv1alpha1.ProgressiveDeployment) []int {
+	return []int{0}
+}
+
+// Init brings up a full-size green workload and points the preview Service at it
+func (s *blueGreenStrategy) Init(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, target Workload) (bool, error) {
+	log := logf.FromContext(ctx)
+
+	if pd.Spec.BlueGreen == nil || pd.Spec.BlueGreen.ActiveService == "" {
+		return false, fmt.Errorf("spec.blueGreen.activeService is required for the blueGreen strategy")
+	}
+
+	active, err := s.getService(ctx, pd, pd.Spec.BlueGreen.ActiveService)
+	if err != nil {
+		log.Error(err, "Failed to get active service", "service", pd.Spec.BlueGreen.ActiveService)
+		return false, err
+	}
+
+	// Pin the active Service to the blue pods so green does not receive live traffic
+	if pd.Status.StableSelector == nil {
+		revisionKey, blueRevision, err := target.RevisionLabel(ctx, s)
+		if err != nil {
+			log.Info("Waiting for blue revision", "reason", err.Error())
+			return false, nil
+		}
+		stableSelector := maps.Clone(active.Spec.Selector)
+		if stableSelector == nil {
+			stableSelector = make(map[string]string)
+		}
+		stableSelector[revisionKey] = blueRevision
+		pd.Status.StableSelector = stableSelector
+	}
+	if err := s.setServiceSelector(ctx, pd, active.Name, pd.Status.StableSelector); err != nil {
+		log.Error(err, "Failed to pin active service to blue")
+		return false, err
+	}
+
+	green, err := s.cloneTarget(ctx, pd, target, "green", target.Replicas(), nil)
+	if err != nil {
+		return false, err
+	}
+
+	if err := s.ensurePreviewService(ctx, pd, active, greenSelector(active.Spec.Selector, trackLabelKey(pd))); err != nil {
+		log.Error(err, "Failed to point preview service at green")
+		return false, err
+	}
+
+	pd.Status.CanaryDeployment = green.Object().GetName()
+	pd.Status.SwitchedAt = nil
+
+	// Green runs at full size, so it is autoscaled like blue
+	if err := s.trackAutoscaler(ctx, pd); err != nil {
+		return false, err
+	}
+	if err := s.mirrorAutoscaler(ctx, pd); err != nil {
+		log.Error(err, "Failed to create autoscaler for green")
+		return false, err
+	}
+
+	log.Info("Green workload is up", "green", pd.Status.CanaryDeployment, "preview", previewServiceName(pd))
+	return true, nil
+}
+
+// ApplyStep waits for every green replica to be available; live traffic stays on blue
+func (s *blueGreenStrategy) ApplyStep(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (bool, error) {
+	log := logf.FromContext(ctx)
+
+	green, err := s.getWorkload(ctx, pd, pd.Status.CanaryDeployment)
+	if err != nil {
+		log.Error(err, "Failed to get green workload")
+		return false, err
+	}
+
+	if !green.Available() {
+		log.Info("Waiting for green workload to become available",
+			"green", pd.Status.CanaryDeployment,
+			"available", green.AvailableReplicas(),
+			"desired", green.Replicas())
+		return false, nil
+	}
+	return true, nil
+}
+
+// Finalize switches the active Service to green and scales blue down after scaleDownDelay
+func (s *blueGreenStrategy) Finalize(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (time.Duration, error) {
+	log := logf.FromContext(ctx)
+
+	var scaleDownDelay time.Duration
+	if pd.Spec.BlueGreen.ScaleDownDelay != nil {
+		scaleDownDelay = pd.Spec.BlueGreen.ScaleDownDelay.Duration
+	}
+
+	// Step 1: Switch the active Service selector to green in a single update
+	if pd.Status.SwitchedAt == nil {
+		active, err := s.getService(ctx, pd, pd.Spec.BlueGreen.ActiveService)
+		if err != nil {
+			log.Error(err, "Failed to get active service")
+			return 0, err
+		}
+		if err := s.setServiceSelector(ctx, pd, active.Name, greenSelector(active.Spec.Selector, trackLabelKey(pd))); err != nil {
+			log.Error(err, "Failed to switch active service to green")
+			return 0, err
+		}
+
+		now := metav1.Now()
+		pd.Status.SwitchedAt = &now
+		pd.Status.CanaryPercentage = 100
+		pd.Status.EffectivePercentage = 100
+		log.Info("Switched active service to green", "service", active.Name, "scaleDownDelay", scaleDownDelay)
+	}
+
+	// Step 2: Keep blue around until scaleDownDelay has elapsed
+	elapsed := time.Since(pd.Status.SwitchedAt.Time)
+	if elapsed < scaleDownDelay {
+		remaining := scaleDownDelay - elapsed
+		log.Info("Keeping blue for instant switch-back", "remaining", remaining)
+		return remaining, nil
+	}
+
+	// Step 3: Scale blue down
+	blue, err := s.getTargetWorkload(ctx, pd)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.scaleWorkload(ctx, blue, 0); err != nil {
+		log.Error(err, "Failed to scale down blue workload")
+		return 0, err
+	}
+
+	log.Info("Scaled blue workload to zero", "blue", blue.Object().GetName())
+	return 0, s.restoreAutoscaler(ctx, pd, true)
+}
+
+// Abort points the active Service back at blue and scales green to zero
+func (s *blueGreenStrategy) Abort(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) error {
+	log := logf.FromContext(ctx)
+
+	// Step 1: Switch the active Service back to blue (blue is still at full size)
+	if pd.Status.StableSelector != nil {
+		if err := s.setServiceSelector(ctx, pd, pd.Spec.BlueGreen.ActiveService, pd.Status.StableSelector); err != nil {
+			log.Error(err, "Failed to switch active service back to blue")
+			return err
+		}
+		log.Info("🔄 Switched back to blue", "service", pd.Spec.BlueGreen.ActiveService)
+	}
+
+	// Step 2: Scale green to 0 replicas (if it exists)
+	green, err := s.getWorkload(ctx, pd, pd.Status.CanaryDeployment)
+	switch {
+	case errors.IsNotFound(err):
+		log.Info("Green workload already deleted, skipping")
+	case err != nil:
+		return err
+	default:
+		if err := s.scaleWorkload(ctx, green, 0); err != nil {
+			log.Error(err, "Failed to scale down green workload")
+			return err
+		}
+	}
+
+	pd.Status.SwitchedAt = nil
+	return s.restoreAutoscaler(ctx, pd, false)
+}
+
+// Plan runs green at the size of blue, without live traffic until the switch-over
+func (s *blueGreenStrategy) Plan(_ *appsv1alpha1.ProgressiveDeployment, replicas int32) []appsv1alpha1.PlannedStep {
+	return []appsv1alpha1.PlannedStep{{Step: 0, Weight: 0, StableReplicas: replicas, CanaryReplicas: replicas}}
+}
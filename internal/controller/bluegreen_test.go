@@ -0,0 +1,79 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+var _ = Describe("Blue-green strategy", func() {
+	It("should select only the green pods for the preview and switched active Service", func() {
+		selector := greenSelector(map[string]string{
+			"app":                "demo-app",
+			podTemplateHashLabel: "6d4b9c7f8",
+		}, defaultTrackLabelKey)
+		Expect(selector).To(Equal(map[string]string{
+			"app":                "demo-app",
+			defaultTrackLabelKey: "green",
+		}))
+	})
+
+	It("should default the preview Service name from the active Service", func() {
+		pd := &appsv1alpha1.ProgressiveDeployment{
+			Spec: appsv1alpha1.ProgressiveDeploymentSpec{
+				Strategy:  appsv1alpha1.StrategyBlueGreen,
+				BlueGreen: &appsv1alpha1.BlueGreenStrategy{ActiveService: "demo-app"},
+			},
+		}
+		Expect(previewServiceName(pd)).To(Equal("demo-app-preview"))
+
+		pd.Spec.BlueGreen.PreviewService = "demo-app-next"
+		Expect(previewServiceName(pd)).To(Equal("demo-app-next"))
+	})
+
+	It("should fail a blueGreen rollout with trafficRouting", func() {
+		ctx := context.Background()
+		pd := &appsv1alpha1.ProgressiveDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "bluegreen-routing", Namespace: "default"},
+			Spec: appsv1alpha1.ProgressiveDeploymentSpec{
+				TargetDeployment: "bluegreen-routing",
+				Strategy:         appsv1alpha1.StrategyBlueGreen,
+				BlueGreen:        &appsv1alpha1.BlueGreenStrategy{ActiveService: "web"},
+				Service:          "web",
+				TrafficRouting:   &appsv1alpha1.TrafficRouting{Nginx: &appsv1alpha1.NginxTrafficRouting{StableIngress: "web"}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, pd)).To(Succeed())
+		defer func() { Expect(k8sClient.Delete(ctx, pd)).To(Succeed()) }()
+		pd.Status.Phase = "Initializing"
+		Expect(k8sClient.Status().Update(ctx, pd)).To(Succeed())
+
+		reconciler := &ProgressiveDeploymentReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		_, err := reconciler.handleInitializing(ctx, pd)
+		Expect(err).To(MatchError(ContainSubstring("only supported by the canary strategy")))
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pd), pd)).To(Succeed())
+		Expect(pd.Status.Phase).To(Equal("Failed"))
+	})
+})
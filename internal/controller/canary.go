@@ -0,0 +1,503 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/utils/ptr"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+// canaryStrategy shifts traffic by splitting the target's replicas between stable and canary
+type canaryStrategy struct {
+	*ProgressiveDeploymentReconciler
+}
+
+// Steps returns the weight of each step of spec.steps, or spec.canarySteps
+func (s *canaryStrategy) Steps(pd *appsv1alpha1.ProgressiveDeployment) []int {
+	if len(pd.Spec.Steps) == 0 {
+		return pd.Spec.CanarySteps
+	}
+
+	// Steps that do not set a weight keep the weight of the previous step
+	weights := make([]int, len(pd.Spec.Steps))
+	weight := 0
+	for i, step := range pd.Spec.Steps {
+		if step.SetWeight != nil {
+			weight = *step.SetWeight
+		}
+		weights[i] = weight
+	}
+	return weights
+}
+
+// activeMirrorRoute returns the mirror route of the current step, if it is a setMirrorRoute step
+func activeMirrorRoute(pd *appsv1alpha1.ProgressiveDeployment) *appsv1alpha1.MirrorRoute {
+	if pd.Status.CurrentStep < 0 || pd.Status.CurrentStep >= len(pd.Spec.Steps) {
+		return nil
+	}
+	return pd.Spec.Steps[pd.Status.CurrentStep].SetMirrorRoute
+}
+
+// activeHeaderRoute returns the header route of the last setHeaderRoute step reached, if any
+func activeHeaderRoute(pd *appsv1alpha1.ProgressiveDeployment) *appsv1alpha1.HeaderRoute {
+	var route *appsv1alpha1.HeaderRoute
+	for i, step := range pd.Spec.Steps {
+		if i > pd.Status.CurrentStep {
+			break
+		}
+		if step.SetHeaderRoute != nil {
+			route = step.SetHeaderRoute
+		}
+	}
+	return route
+}
+
+// Init creates the canary workload
+func (s *canaryStrategy) Init(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, target Workload) (bool, error) {
+	router, err := s.routerFor(pd)
+	if err != nil {
+		return false, err
+	}
+	if err := validateSteps(pd, router); err != nil {
+		return false, err
+	}
+
+	canary, err := s.createCanary(ctx, pd, target)
+	if err != nil {
+		return false, err
+	}
+	pd.Status.CanaryDeployment = canary.Object().GetName()
+
+	// Remember the target's size, stable gives it up to the canary step by step
+	if pd.Spec.DynamicStableScale && !routesByReplicas(pd) && pd.Status.StableReplicas == nil {
+		pd.Status.StableReplicas = ptr.To(target.Replicas())
+	}
+
+	if err := s.trackAutoscaler(ctx, pd); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ApplyStep sends the current canary percentage of the traffic to the canary through the router
+func (s *canaryStrategy) ApplyStep(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (bool, error) {
+	log := logf.FromContext(ctx)
+
+	router, err := s.routerFor(pd)
+	if err != nil {
+		return false, err
+	}
+
+	// A routed canary has to be ready before requests are sent or mirrored to it
+	mirror := activeMirrorRoute(pd)
+	if !routesByReplicas(pd) {
+		load := pd.Status.CanaryPercentage
+		if mirror != nil {
+			load = max(load, mirror.Percentage)
+		}
+		ready, err := s.scaleRoutedCanary(ctx, pd, load)
+		if err != nil || !ready {
+			return false, err
+		}
+	}
+
+	// Adjust traffic based on current canary percentage
+	if err := router.SetWeight(ctx, pd, pd.Status.CanaryPercentage); err != nil {
+		log.Error(err, "Failed to adjust traffic", "router", router.Name())
+		return false, err
+	}
+
+	// Mirror requests to the canary during a setMirrorRoute step
+	if mirror != nil {
+		mirrorRouter, ok := router.(MirrorRouter)
+		if !ok {
+			return false, fmt.Errorf("router %s does not support setMirrorRoute steps", router.Name())
+		}
+		if err := mirrorRouter.SetMirrorRoute(ctx, pd, mirror); err != nil {
+			log.Error(err, "Failed to set mirror route", "router", router.Name())
+			return false, err
+		}
+	}
+
+	// Keep the requests matched by a header route on the canary
+	if route := activeHeaderRoute(pd); route != nil {
+		headerRouter, ok := router.(HeaderRouter)
+		if !ok {
+			return false, fmt.Errorf("router %s does not support setHeaderRoute steps", router.Name())
+		}
+		if err := headerRouter.SetHeaderRoute(ctx, pd, route); err != nil {
+			log.Error(err, "Failed to set header route", "router", router.Name())
+			return false, err
+		}
+	}
+
+	// Report the share of the traffic the canary actually gets
+	effective, err := s.effectivePercentage(ctx, pd)
+	if err != nil {
+		return false, err
+	}
+	if effective != pd.Status.CanaryPercentage {
+		log.Info("Canary share differs from the step weight",
+			"canaryPercentage", pd.Status.CanaryPercentage, "effectivePercentage", effective)
+	}
+	pd.Status.EffectivePercentage = effective
+
+	// Stable only gives up replicas once the router no longer sends it their share
+	if !routesByReplicas(pd) {
+		if err := s.shrinkStable(ctx, pd, pd.Status.CanaryPercentage); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// Finalize makes the canary the only version serving traffic and hands it the original
+// autoscaler bounds. With a traffic router the canary is first scaled to the full size
+// of the target, then the router's objects are removed and stable is scaled to zero
+func (s *canaryStrategy) Finalize(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (time.Duration, error) {
+	log := logf.FromContext(ctx)
+
+	router, err := s.routerFor(pd)
+	if err != nil {
+		return 0, err
+	}
+
+	// Step 1: Bring a routed canary up to the size of the target
+	var target Workload
+	if !routesByReplicas(pd) {
+		target, err = s.getTargetWorkload(ctx, pd)
+		if err != nil {
+			return 0, err
+		}
+		canary, err := s.getCanary(ctx, pd)
+		if err != nil {
+			return 0, err
+		}
+		fullSize := max(target.Replicas(), canary.Replicas())
+		if pd.Status.StableReplicas != nil {
+			fullSize = *pd.Status.StableReplicas
+		}
+		if canary.Replicas() != fullSize {
+			if err := s.scaleWorkload(ctx, canary, fullSize); err != nil {
+				log.Error(err, "Failed to scale up canary workload")
+				return 0, err
+			}
+			log.Info("Scaled canary workload to full size", "replicas", fullSize)
+		}
+		if !canary.Available() {
+			log.Info("Waiting for canary workload to become available before promotion")
+			return readinessPollInterval, nil
+		}
+	}
+
+	// Step 2: Let the primary route carry the traffic again
+	if err := router.Promote(ctx, pd); err != nil {
+		log.Error(err, "Failed to promote traffic", "router", router.Name())
+		return 0, err
+	}
+
+	// Step 3: Scale stable down, it no longer receives requests
+	if target != nil && target.Replicas() > 0 {
+		if err := s.scaleWorkload(ctx, target, 0); err != nil {
+			log.Error(err, "Failed to scale down stable workload")
+			return 0, err
+		}
+		log.Info("✅ Scaled stable workload to zero")
+	}
+
+	pd.Status.StableReplicas = nil
+	return 0, s.restoreAutoscaler(ctx, pd, true)
+}
+
+// Abort restores the stable workload to full capacity and scales the canary to zero
+func (s *canaryStrategy) Abort(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) error {
+	log := logf.FromContext(ctx)
+
+	// Step 1: Send all requests back to stable and get the target (stable) workload
+	router, err := s.routerFor(pd)
+	if err != nil {
+		return err
+	}
+	if err := router.Abort(ctx, pd); err != nil {
+		log.Error(err, "Failed to reset traffic routing", "router", router.Name())
+		return err
+	}
+	target, err := s.getTargetWorkload(ctx, pd)
+	if err != nil {
+		log.Error(err, "Failed to get target workload during rollback")
+		return err
+	}
+
+	// Step 2: Get the canary workload
+	canary, err := s.getCanary(ctx, pd)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Error(err, "Failed to get canary workload during rollback")
+			return err
+		}
+		log.Info("Canary workload already deleted, skipping")
+		canary = nil
+	}
+
+	// Step 3: Calculate original total replicas
+	// We need to restore stable to full capacity
+	originalReplicas := target.Replicas()
+	if canary != nil && routesByReplicas(pd) {
+		// Take the current canary replicas back into the total
+		originalReplicas = replicaSplitFor(pd).total(target.Replicas(), canary.Replicas())
+	}
+	if pd.Status.StableReplicas != nil {
+		originalReplicas = *pd.Status.StableReplicas
+	}
+
+	log.Info("Rolling back traffic distribution",
+		"stableReplicas", originalReplicas,
+		"canaryReplicas", 0)
+
+	// Step 4: Restore stable workload to full replicas
+	if err := s.scaleWorkload(ctx, target, originalReplicas); err != nil {
+		log.Error(err, "Failed to restore stable workload replicas")
+		return err
+	}
+	log.Info("✅ Restored stable workload to full capacity", "replicas", originalReplicas)
+
+	// Step 5: Scale canary to 0 replicas (if it exists)
+	if canary != nil {
+		if err := s.scaleWorkload(ctx, canary, 0); err != nil {
+			log.Error(err, "Failed to scale down canary workload")
+			return err
+		}
+		log.Info("✅ Scaled canary workload to zero", "name", canary.Object().GetName())
+	}
+
+	// Step 6: Give the stable autoscaler its original bounds back
+	pd.Status.StableReplicas = nil
+	return s.restoreAutoscaler(ctx, pd, false)
+}
+
+// createCanary creates a canary workload as a clone of the target
+func (s *canaryStrategy) createCanary(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, target Workload) (Workload, error) {
+	if len(s.Steps(pd)) == 0 {
+		return nil, fmt.Errorf("spec.canarySteps or spec.steps must not be empty for the canary strategy")
+	}
+
+	// Start with 0 replicas - we'll adjust based on canary percentage
+	return s.cloneTarget(ctx, pd, target, "canary", 0, canaryScheduling(pd))
+}
+
+// getCanary fetches the workload recorded in status.canaryDeployment
+func (s *canaryStrategy) getCanary(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (Workload, error) {
+	return s.getWorkload(ctx, pd, pd.Status.CanaryDeployment)
+}
+
+// Plan splits the target's replicas at each step weight the way ApplyStep would
+func (s *canaryStrategy) Plan(pd *appsv1alpha1.ProgressiveDeployment, replicas int32) []appsv1alpha1.PlannedStep {
+	split := replicaSplitFor(pd)
+	steps := s.Steps(pd)
+	plan := make([]appsv1alpha1.PlannedStep, 0, len(steps))
+	for i, weight := range steps {
+		step := appsv1alpha1.PlannedStep{Step: i, Weight: weight}
+		switch {
+		case routesByReplicas(pd):
+			step.StableReplicas, step.CanaryReplicas = split.distribute(replicas, weight)
+			step.EffectivePercentage = replicaPercentage(step.StableReplicas, step.CanaryReplicas)
+		case pd.Spec.DynamicStableScale:
+			step.StableReplicas = replicas - split.canaryReplicas(replicas, weight)
+			step.CanaryReplicas = routedCanaryReplicas(split, replicas, weight)
+			step.EffectivePercentage = weight
+		default:
+			step.StableReplicas = replicas
+			step.CanaryReplicas = routedCanaryReplicas(split, replicas, weight)
+			step.EffectivePercentage = weight
+		}
+		plan = append(plan, step)
+	}
+	return plan
+}
+
+// routesByReplicas reports whether the traffic split follows the replica split.
+// Otherwise a traffic router splits the requests and stable keeps its full size
+func routesByReplicas(pd *appsv1alpha1.ProgressiveDeployment) bool {
+	return pd.Spec.TrafficRouting == nil
+}
+
+// routedCanaryReplicas returns the canary replicas needed to serve weight percent of the
+// requests stable serves with stableReplicas. A routed canary keeps at least one replica,
+// so requests matched by header or cookie always have somewhere to go
+func routedCanaryReplicas(split replicaSplit, stableReplicas int32, weight int) int32 {
+	return max(split.canaryReplicas(stableReplicas, weight), 1)
+}
+
+// scaleRoutedCanary sizes the canary for the share of requests the router sends it,
+// in proportion to the target's size. It returns false until the canary is available
+func (s *canaryStrategy) scaleRoutedCanary(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, weight int) (bool, error) {
+	log := logf.FromContext(ctx)
+
+	target, err := s.getTargetWorkload(ctx, pd)
+	if err != nil {
+		return false, err
+	}
+	canary, err := s.getCanary(ctx, pd)
+	if err != nil {
+		log.Error(err, "Failed to get canary workload")
+		return false, err
+	}
+
+	size := target.Replicas()
+	if pd.Status.StableReplicas != nil {
+		size = *pd.Status.StableReplicas
+	}
+	replicas := routedCanaryReplicas(replicaSplitFor(pd), size, weight)
+	if canary.Replicas() < replicas {
+		if err := s.scaleWorkload(ctx, canary, replicas); err != nil {
+			log.Error(err, "Failed to update canary workload replicas")
+			return false, err
+		}
+		log.Info("Updated canary workload", "replicas", replicas, "canaryPercentage", weight)
+	}
+
+	if !canary.Available() {
+		log.Info("Waiting for canary workload to become available", "replicas", replicas)
+		return false, nil
+	}
+	return true, nil
+}
+
+// effectivePercentage returns the share of the requests the canary gets: the step weight
+// with a traffic router, otherwise its share of the replicas
+func (s *canaryStrategy) effectivePercentage(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (int, error) {
+	if !routesByReplicas(pd) {
+		return pd.Status.CanaryPercentage, nil
+	}
+	target, err := s.getTargetWorkload(ctx, pd)
+	if err != nil {
+		return 0, err
+	}
+	canary, err := s.getCanary(ctx, pd)
+	if err != nil {
+		return 0, err
+	}
+	return replicaPercentage(target.Replicas(), canary.Replicas()), nil
+}
+
+// shrinkStable scales stable down to its share of the target's replicas once the router
+// sends the canary weight percent of the requests (spec.dynamicStableScale only)
+func (s *canaryStrategy) shrinkStable(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, weight int) error {
+	if pd.Status.StableReplicas == nil {
+		return nil
+	}
+	log := logf.FromContext(ctx)
+
+	target, err := s.getTargetWorkload(ctx, pd)
+	if err != nil {
+		return err
+	}
+	stableReplicas := *pd.Status.StableReplicas - replicaSplitFor(pd).canaryReplicas(*pd.Status.StableReplicas, weight)
+	if target.Replicas() > stableReplicas {
+		if err := s.scaleWorkload(ctx, target, stableReplicas); err != nil {
+			log.Error(err, "Failed to update stable workload replicas")
+			return err
+		}
+		log.Info("Updated stable workload", "replicas", stableReplicas, "canaryPercentage", weight)
+	}
+
+	// Keep autoscalers from scaling either side out of its share
+	return s.splitAutoscaler(ctx, pd, weight)
+}
+
+// replicaSplit turns a step's weight into canary replicas as configured in spec.canary
+type replicaSplit struct {
+	minCanary int32
+	maxSurge  int32
+	rounding  string
+}
+
+// replicaSplitFor returns the replica split of spec.canary, or the default split
+// rounding up without a minimum or surge
+func replicaSplitFor(pd *appsv1alpha1.ProgressiveDeployment) replicaSplit {
+	if pd.Spec.Canary == nil {
+		return replicaSplit{}
+	}
+	return replicaSplit{
+		minCanary: pd.Spec.Canary.MinReplicas,
+		maxSurge:  pd.Spec.Canary.MaxSurge,
+		rounding:  pd.Spec.Canary.Rounding,
+	}
+}
+
+// canaryReplicas returns the canary's share of total replicas at weight percent
+func (s replicaSplit) canaryReplicas(total int32, weight int) int32 {
+	if weight <= 0 {
+		return 0
+	}
+	if weight >= 100 {
+		return total
+	}
+
+	share := float64(total) * float64(weight) / 100.0
+	var replicas int32
+	switch s.rounding {
+	case appsv1alpha1.RoundingFloor:
+		replicas = int32(math.Floor(share))
+	case appsv1alpha1.RoundingNearest:
+		replicas = int32(math.Round(share))
+	default:
+		// Round up to ensure traffic gets through
+		replicas = int32(math.Ceil(share))
+	}
+	return min(max(replicas, s.minCanary), total)
+}
+
+// distribute splits total replicas between stable and canary at weight percent. The first
+// maxSurge canary replicas run on top of stable instead of being taken from it
+func (s replicaSplit) distribute(total int32, weight int) (stableReplicas, canaryReplicas int32) {
+	canaryReplicas = s.canaryReplicas(total, weight)
+	if weight >= 100 {
+		return 0, canaryReplicas
+	}
+	return total - max(canaryReplicas-s.maxSurge, 0), canaryReplicas
+}
+
+// total returns the replicas a distribution of stable and canary replicas was made from,
+// so the total does not grow by the surge between steps
+func (s replicaSplit) total(stableReplicas, canaryReplicas int32) int32 {
+	if stableReplicas == 0 {
+		return canaryReplicas
+	}
+	return stableReplicas + max(canaryReplicas-s.maxSurge, 0)
+}
+
+// replicaPercentage returns the canary's share of the running replicas, in percent
+func replicaPercentage(stableReplicas, canaryReplicas int32) int {
+	if stableReplicas+canaryReplicas == 0 {
+		return 0
+	}
+	return int(math.Round(float64(canaryReplicas) * 100 / float64(stableReplicas+canaryReplicas)))
+}
+
+// calculateReplicaDistribution calculates stable and canary replica counts with the default split
+func calculateReplicaDistribution(totalReplicas int, canaryPercentage int) (stableReplicas, canaryReplicas int32) {
+	return replicaSplit{}.distribute(int32(max(totalReplicas, 0)), canaryPercentage)
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+var _ = Describe("Replica split", func() {
+	DescribeTable("splitting the replicas between stable and canary",
+		func(split replicaSplit, total int32, weight int, stable, canary int32) {
+			stableReplicas, canaryReplicas := split.distribute(total, weight)
+			Expect(stableReplicas).To(Equal(stable))
+			Expect(canaryReplicas).To(Equal(canary))
+			Expect(split.total(stableReplicas, canaryReplicas)).To(Equal(total))
+		},
+		Entry("rounds up by default", replicaSplit{}, int32(2), 10, int32(1), int32(1)),
+		Entry("rounds down", replicaSplit{rounding: appsv1alpha1.RoundingFloor}, int32(2), 10, int32(2), int32(0)),
+		Entry("rounds to the nearest replica", replicaSplit{rounding: appsv1alpha1.RoundingNearest}, int32(10), 14, int32(9), int32(1)),
+		Entry("keeps the minimum canary replicas", replicaSplit{minCanary: 2, rounding: appsv1alpha1.RoundingFloor}, int32(10), 5, int32(8), int32(2)),
+		Entry("runs the first canary replicas as surge", replicaSplit{maxSurge: 1}, int32(4), 25, int32(4), int32(1)),
+		Entry("takes replicas beyond the surge from stable", replicaSplit{maxSurge: 1}, int32(4), 50, int32(3), int32(2)),
+		Entry("moves every replica at full weight", replicaSplit{maxSurge: 1}, int32(4), 100, int32(0), int32(4)),
+		Entry("runs no canary at zero weight", replicaSplit{minCanary: 2}, int32(4), 0, int32(4), int32(0)),
+	)
+
+	It("should report the canary's share of the replicas", func() {
+		Expect(replicaPercentage(1, 1)).To(Equal(50))
+		Expect(replicaPercentage(4, 1)).To(Equal(20))
+		Expect(replicaPercentage(0, 0)).To(Equal(0))
+	})
+
+	It("should read the split from spec.canary", func() {
+		pd := &appsv1alpha1.ProgressiveDeployment{Spec: appsv1alpha1.ProgressiveDeploymentSpec{
+			Canary: &appsv1alpha1.CanaryOptions{MinReplicas: 1, MaxSurge: 2, Rounding: appsv1alpha1.RoundingNearest},
+		}}
+		Expect(replicaSplitFor(pd)).To(Equal(replicaSplit{minCanary: 1, maxSurge: 2, rounding: appsv1alpha1.RoundingNearest}))
+	})
+})
@@ -0,0 +1,186 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+	"github.com/ghanatava/bg-switch/internal/analysis"
+)
+
+// Reasons of the events recorded for spec.dryRun
+const (
+	reasonDryRunPlanned = "DryRunPlanned"
+	reasonDryRunIgnored = "DryRunIgnored"
+)
+
+// dryRunApplies reports whether spec.dryRun plans the rollout, which it does before the rollout
+// starts and once it has finished. A rollout in flight keeps running: holding it would freeze
+// its traffic split at whatever step it reached
+func dryRunApplies(pd *appsv1alpha1.ProgressiveDeployment) bool {
+	return pd.Status.Phase == "Initializing" || isTerminalPhase(pd.Status.Phase)
+}
+
+// handleDryRun plans the rollout in status.plan without touching any workload, router or Service.
+// The plan is refreshed every stepDuration so the metric values stay current
+func (r *ProgressiveDeploymentReconciler) handleDryRun(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+	log.Info("Planning rollout (dry run)", "strategy", pd.Spec.Strategy)
+
+	strategy, err := r.strategyFor(pd)
+	if err != nil {
+		r.event(pd, corev1.EventTypeWarning, reasonDryRunPlanned, "Cannot plan the rollout: %v", err)
+		return ctrl.Result{}, err
+	}
+
+	// Step 1: Read the size the rollout would start from
+	replicas, err := r.plannedReplicas(ctx, pd)
+	if err != nil {
+		r.event(pd, corev1.EventTypeWarning, reasonDryRunPlanned, "Cannot plan the rollout: %v", err)
+		return ctrl.Result{}, err
+	}
+
+	// Step 2: Split the replicas at each step and check the metrics as they are now
+	plan := &appsv1alpha1.RolloutPlan{
+		PlannedAt: metav1.Now(),
+		Replicas:  replicas,
+		Steps:     strategy.Plan(pd, replicas),
+		Checks:    r.planChecks(ctx, pd),
+	}
+	plan.Verdict = planVerdict(plan.Checks)
+
+	// Step 3: Report the plan in status and as an event
+	pd.Status.Plan = plan
+	if err := r.updateStatus(ctx, pd); err != nil {
+		return ctrl.Result{}, err
+	}
+	r.event(pd, corev1.EventTypeNormal, reasonDryRunPlanned, "%s", describePlan(plan))
+	log.Info("Planned rollout (dry run)", "steps", len(plan.Steps), "replicas", replicas, "verdict", plan.Verdict)
+
+	requeueAfter := pd.Spec.StepDuration.Duration
+	if requeueAfter < readinessPollInterval {
+		requeueAfter = readinessPollInterval
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// plannedReplicas returns the size of the target a rollout would start from
+func (r *ProgressiveDeploymentReconciler) plannedReplicas(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (int32, error) {
+	if pd.Spec.GitOps {
+		source, err := r.getSourceWorkload(ctx, pd)
+		if err != nil {
+			return 0, err
+		}
+		return desiredReplicas(source)
+	}
+	target, err := r.getTargetWorkload(ctx, pd)
+	if err != nil {
+		return 0, err
+	}
+	return target.Replicas(), nil
+}
+
+// planChecks runs the configured metric queries and compares their values with the thresholds.
+// A query that fails is reported in the check instead of failing the plan
+func (r *ProgressiveDeploymentReconciler) planChecks(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) []appsv1alpha1.PlannedCheck {
+	checks := analysis.ChecksFor(pd.Spec.Metrics)
+	if len(checks) == 0 {
+		return nil
+	}
+
+	metricsClient, err := r.metricsClient(ctx, pd)
+	if err != nil {
+		planned := make([]appsv1alpha1.PlannedCheck, 0, len(checks))
+		for _, check := range checks {
+			planned = append(planned, appsv1alpha1.PlannedCheck{
+				Name:      check.Name,
+				Query:     check.Query,
+				Threshold: check.Threshold,
+				Error:     err.Error(),
+			})
+		}
+		return planned
+	}
+
+	planned := make([]appsv1alpha1.PlannedCheck, 0, len(checks))
+	for _, result := range analysis.Analyze(ctx, metricsClient, checks, time.Now(), r.analysisOptions()).Checks {
+		check := appsv1alpha1.PlannedCheck{
+			Name:         result.Name,
+			Query:        result.Query,
+			Threshold:    result.Threshold,
+			Passed:       result.Passed,
+			Inconclusive: result.Inconclusive,
+		}
+		if result.Err != nil {
+			check.Error = result.Err.Error()
+		}
+		if result.Measured {
+			check.Value = &result.Value
+		}
+		planned = append(planned, check)
+	}
+	return planned
+}
+
+// planVerdict returns what an analysis would decide, like in handleAnalyzing: a check that
+// failed or could not be queried rolls back, and otherwise a query that did not complete in
+// time makes the analysis inconclusive
+func planVerdict(checks []appsv1alpha1.PlannedCheck) string {
+	verdict := appsv1alpha1.VerdictPromote
+	for _, check := range checks {
+		switch {
+		case check.Passed:
+		case check.Inconclusive:
+			verdict = appsv1alpha1.VerdictInconclusive
+		default:
+			return appsv1alpha1.VerdictRollBack
+		}
+	}
+	return verdict
+}
+
+// describePlan summarizes a plan in one line
+func describePlan(plan *appsv1alpha1.RolloutPlan) string {
+	steps := make([]string, 0, len(plan.Steps))
+	for _, step := range plan.Steps {
+		steps = append(steps, fmt.Sprintf("%d%% (%d/%d)", step.Weight, step.StableReplicas, step.CanaryReplicas))
+	}
+	failed := make([]string, 0, len(plan.Checks))
+	for _, check := range plan.Checks {
+		if !check.Passed {
+			failed = append(failed, check.Name)
+		}
+	}
+
+	message := fmt.Sprintf("Would run %d steps from %d replicas, stable/canary: %s", len(plan.Steps), plan.Replicas, strings.Join(steps, ", "))
+	switch plan.Verdict {
+	case appsv1alpha1.VerdictRollBack:
+		return fmt.Sprintf("%s; with the current metrics the analysis would roll back (%s)", message, strings.Join(failed, ", "))
+	case appsv1alpha1.VerdictInconclusive:
+		return fmt.Sprintf("%s; the analysis would be inconclusive, queries did not complete in time (%s)", message, strings.Join(failed, ", "))
+	}
+	return message + "; with the current metrics the analysis would promote"
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+var _ = Describe("Dry run", func() {
+	ctx := context.Background()
+
+	DescribeTable("planning the replicas of each canary step",
+		func(spec appsv1alpha1.ProgressiveDeploymentSpec, expected []appsv1alpha1.PlannedStep) {
+			pd := &appsv1alpha1.ProgressiveDeployment{Spec: spec}
+			Expect((&canaryStrategy{}).Plan(pd, 10)).To(Equal(expected))
+		},
+		Entry("splits the replicas without a traffic router",
+			appsv1alpha1.ProgressiveDeploymentSpec{CanarySteps: []int{25, 100}},
+			[]appsv1alpha1.PlannedStep{
+				{Step: 0, Weight: 25, StableReplicas: 7, CanaryReplicas: 3, EffectivePercentage: 30},
+				{Step: 1, Weight: 100, StableReplicas: 0, CanaryReplicas: 10, EffectivePercentage: 100},
+			}),
+		Entry("keeps stable at full size behind a traffic router",
+			appsv1alpha1.ProgressiveDeploymentSpec{
+				CanarySteps:    []int{20},
+				TrafficRouting: &appsv1alpha1.TrafficRouting{Nginx: &appsv1alpha1.NginxTrafficRouting{StableIngress: "web"}},
+			},
+			[]appsv1alpha1.PlannedStep{{Step: 0, Weight: 20, StableReplicas: 10, CanaryReplicas: 2, EffectivePercentage: 20}}),
+		Entry("shrinks stable behind a traffic router with dynamicStableScale",
+			appsv1alpha1.ProgressiveDeploymentSpec{
+				CanarySteps:        []int{20},
+				DynamicStableScale: true,
+				TrafficRouting:     &appsv1alpha1.TrafficRouting{Nginx: &appsv1alpha1.NginxTrafficRouting{StableIngress: "web"}},
+			},
+			[]appsv1alpha1.PlannedStep{{Step: 0, Weight: 20, StableReplicas: 8, CanaryReplicas: 2, EffectivePercentage: 20}}),
+	)
+
+	It("should plan green at the size of blue", func() {
+		plan := (&blueGreenStrategy{}).Plan(&appsv1alpha1.ProgressiveDeployment{}, 4)
+		Expect(plan).To(Equal([]appsv1alpha1.PlannedStep{{Step: 0, Weight: 0, StableReplicas: 4, CanaryReplicas: 4}}))
+	})
+
+	DescribeTable("deciding what an analysis would do",
+		func(checks []appsv1alpha1.PlannedCheck, expected string) {
+			Expect(planVerdict(checks)).To(Equal(expected))
+		},
+		Entry("promotes without metrics", nil, appsv1alpha1.VerdictPromote),
+		Entry("promotes when every check passes",
+			[]appsv1alpha1.PlannedCheck{{Name: "errorRate", Passed: true}, {Name: "latency", Passed: true}}, appsv1alpha1.VerdictPromote),
+		Entry("rolls back when a check fails",
+			[]appsv1alpha1.PlannedCheck{{Name: "errorRate", Passed: true}, {Name: "latency"}}, appsv1alpha1.VerdictRollBack),
+		Entry("rolls back when a query fails",
+			[]appsv1alpha1.PlannedCheck{{Name: "errorRate", Error: "no data returned from query"}}, appsv1alpha1.VerdictRollBack),
+		Entry("is inconclusive when a query does not complete in time",
+			[]appsv1alpha1.PlannedCheck{{Name: "errorRate", Passed: true}, {Name: "latency", Inconclusive: true}}, appsv1alpha1.VerdictInconclusive),
+		Entry("rolls back when a check fails while another query is slow",
+			[]appsv1alpha1.PlannedCheck{{Name: "errorRate", Inconclusive: true}, {Name: "latency"}}, appsv1alpha1.VerdictRollBack),
+	)
+
+	It("should check the current metric values against their thresholds", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			value := "0.02"
+			if r.FormValue("query") == "latency" {
+				value = "0.8"
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1700000000,%q]}]}}`, value)
+		}))
+		defer server.Close()
+
+		pd := &appsv1alpha1.ProgressiveDeployment{Spec: appsv1alpha1.ProgressiveDeploymentSpec{
+			Metrics: appsv1alpha1.MetricsConfig{
+				PrometheusURL: server.URL,
+				ErrorRate:     appsv1alpha1.MetricThreshold{Query: "errors", Threshold: 0.05},
+				Latency:       appsv1alpha1.MetricThreshold{Query: "latency", Threshold: 0.5},
+			},
+		}}
+		checks := (&ProgressiveDeploymentReconciler{}).planChecks(ctx, pd)
+		Expect(checks).To(HaveLen(2))
+		Expect(*checks[0].Value).To(Equal(0.02))
+		Expect(checks[0].Passed).To(BeTrue())
+		Expect(*checks[1].Value).To(Equal(0.8))
+		Expect(checks[1].Passed).To(BeFalse())
+	})
+
+	Context("When a ProgressiveDeployment is in dry-run mode", func() {
+		var pd *appsv1alpha1.ProgressiveDeployment
+		var target *appsv1.Deployment
+
+		BeforeEach(func() {
+			labels := map[string]string{"app": "billing"}
+			target = &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "billing", Namespace: "default"},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: ptr.To[int32](10),
+					Selector: &metav1.LabelSelector{MatchLabels: labels},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: labels},
+						Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "billing", Image: "billing:1.0"}}},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, target)).To(Succeed())
+
+			pd = &appsv1alpha1.ProgressiveDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "billing", Namespace: "default"},
+				Spec: appsv1alpha1.ProgressiveDeploymentSpec{
+					TargetDeployment: "billing",
+					CanarySteps:      []int{10, 50, 100},
+					DryRun:           true,
+				},
+			}
+			Expect(k8sClient.Create(ctx, pd)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.Delete(ctx, pd)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, target)).To(Succeed())
+		})
+
+		It("should report the plan without touching any workload", func() {
+			recorder := record.NewFakeRecorder(10)
+			reconciler := &ProgressiveDeploymentReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Recorder: recorder}
+			for range 3 {
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pd)})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pd), pd)).To(Succeed())
+			Expect(pd.Status.Phase).To(Equal("Initializing"))
+			Expect(pd.Status.Plan).NotTo(BeNil())
+			Expect(pd.Status.Plan.Replicas).To(Equal(int32(10)))
+			Expect(pd.Status.Plan.Steps).To(HaveLen(3))
+			Expect(pd.Status.Plan.Steps[0].CanaryReplicas).To(Equal(int32(1)))
+			Expect(pd.Status.Plan.Verdict).To(Equal(appsv1alpha1.VerdictPromote))
+			Expect(recorder.Events).To(Receive(ContainSubstring(reasonDryRunPlanned)))
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(target), target)).To(Succeed())
+			Expect(*target.Spec.Replicas).To(Equal(int32(10)))
+			canary := &appsv1.Deployment{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "billing-canary"}, canary)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should keep a rollout in flight running", func() {
+			pd.Spec.StepDuration = metav1.Duration{Duration: time.Minute}
+			Expect(k8sClient.Update(ctx, pd)).To(Succeed())
+			now := metav1.Now()
+			pd.Status = appsv1alpha1.ProgressiveDeploymentStatus{Phase: "Analyzing", CanaryPercentage: 10, LastAnalysisTime: &now}
+			Expect(k8sClient.Status().Update(ctx, pd)).To(Succeed())
+
+			recorder := record.NewFakeRecorder(10)
+			reconciler := &ProgressiveDeploymentReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Recorder: recorder}
+			request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pd)}
+
+			// The first reconcile records what the rollout runs and starts over
+			result, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Requeue).To(BeTrue())
+
+			result, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically("~", time.Minute, time.Second))
+			Expect(recorder.Events).To(Receive(ContainSubstring(reasonDryRunIgnored)))
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pd), pd)).To(Succeed())
+			Expect(pd.Status.Phase).To(Equal("Analyzing"))
+			Expect(pd.Status.Plan).To(BeNil())
+		})
+	})
+})
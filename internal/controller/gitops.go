@@ -0,0 +1,313 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+const (
+	// replicasAnnotation on the target declares the size of the workloads bg-switch manages in
+	// GitOps mode, where the target itself is kept at zero replicas in Git
+	replicasAnnotation = "bgswitch.io/replicas"
+
+	// healthStatusAnnotation is the rollout's health in Argo CD terms: Healthy, Progressing,
+	// Suspended or Degraded
+	healthStatusAnnotation = "bgswitch.io/health-status"
+	// healthMessageAnnotation explains healthStatusAnnotation
+	healthMessageAnnotation = "bgswitch.io/health-message"
+
+	// stableRole names the stable workload managed in GitOps mode
+	stableRole = "stable"
+)
+
+// getSourceWorkload fetches the workload referenced by spec.targetRef or spec.targetDeployment.
+// Outside GitOps mode it is the stable workload as well
+func (r *ProgressiveDeploymentReconciler) getSourceWorkload(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (Workload, error) {
+	log := logf.FromContext(ctx)
+	ref := targetRef(pd)
+
+	workload, err := r.getWorkload(ctx, pd, ref.Name)
+	if err != nil {
+		log.Error(err, "Failed to get target workload", "kind", ref.Kind, "name", ref.Name)
+		return nil, err
+	}
+	return workload, nil
+}
+
+// desiredReplicas returns the size of the managed workloads: the target's bgswitch.io/replicas
+// annotation, or its replicas while it has not been scaled down in Git yet
+func desiredReplicas(source Workload) (int32, error) {
+	value, ok := source.Object().GetAnnotations()[replicasAnnotation]
+	if !ok {
+		if source.Replicas() == 0 {
+			return 0, fmt.Errorf("target %s runs no replicas, declare the size in its %s annotation",
+				source.Object().GetName(), replicasAnnotation)
+		}
+		return source.Replicas(), nil
+	}
+
+	replicas, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || replicas < 1 {
+		return 0, fmt.Errorf("annotation %s=%q on target %s is not a positive number of replicas",
+			replicasAnnotation, value, source.Object().GetName())
+	}
+	return int32(replicas), nil
+}
+
+// ensureManagedStable creates <target>-stable from the target in GitOps mode and returns it.
+// It returns false until the managed stable workload is available
+func (r *ProgressiveDeploymentReconciler) ensureManagedStable(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (Workload, bool, error) {
+	log := logf.FromContext(ctx)
+
+	source, err := r.getSourceWorkload(ctx, pd)
+	if err != nil {
+		return nil, false, err
+	}
+	replicas, err := desiredReplicas(source)
+	if err != nil {
+		return nil, false, err
+	}
+
+	stable, err := r.cloneTarget(ctx, pd, source, stableRole, replicas, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	pd.Status.StableDeployment = stable.Object().GetName()
+	setTargetScaledDownCondition(pd, source)
+
+	if !stable.Available() {
+		log.Info("Waiting for managed stable workload to become available", "name", pd.Status.StableDeployment)
+		return stable, false, nil
+	}
+	return stable, true, nil
+}
+
+// promotedStableTemplate returns the pod template of the promoted new version prepared for the
+// managed stable workload: its pods are tracked as stable, and where spec.canary.scheduling
+// placed the canary elsewhere they keep the scheduling of the stable pods
+func promotedStableTemplate(pd *appsv1alpha1.ProgressiveDeployment, stable, newVersion Workload) *corev1.PodTemplateSpec {
+	template := newVersion.Template().DeepCopy()
+	if template.Labels == nil {
+		template.Labels = make(map[string]string)
+	}
+	template.Labels[trackLabelKey(pd)] = stableRole
+	rewriteSchedulingSelectors(template, newVersion.Template().Labels)
+
+	override := canaryScheduling(pd)
+	if override == nil {
+		return template
+	}
+	current := &stable.Template().Spec
+	if override.NodeSelector != nil {
+		template.Spec.NodeSelector = current.NodeSelector
+	}
+	if override.Tolerations != nil {
+		template.Spec.Tolerations = current.Tolerations
+	}
+	if override.Affinity != nil {
+		template.Spec.Affinity = current.Affinity.DeepCopy()
+	}
+	if override.TopologySpreadConstraints != nil {
+		template.Spec.TopologySpreadConstraints = current.TopologySpreadConstraints
+	}
+	if override.PriorityClassName != "" {
+		template.Spec.PriorityClassName = current.PriorityClassName
+	}
+	return template
+}
+
+// rebaseManagedStable hands a completed rollout's new version over to <target>-stable, so the
+// next rollout starts from it: the stable workload takes the promoted pod template at full
+// size, traffic moves back to it and the new version is scaled to zero. It returns when to
+// reconcile again: after waiting for the stable workload, or right away once the status
+// was written. It does nothing once the new version runs no replicas
+func (r *ProgressiveDeploymentReconciler) rebaseManagedStable(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, strategy Strategy) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+	if pd.Status.CanaryDeployment == "" {
+		return ctrl.Result{}, nil
+	}
+
+	newVersion, err := r.getWorkload(ctx, pd, pd.Status.CanaryDeployment)
+	if errors.IsNotFound(err) || (err == nil && newVersion.Replicas() == 0) {
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	stable, err := r.getTargetWorkload(ctx, pd)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	source, err := r.getSourceWorkload(ctx, pd)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	replicas, err := desiredReplicas(source)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Step 1: Run the promoted template on the stable workload at full size
+	template := promotedStableTemplate(pd, stable, newVersion)
+	if stable.Replicas() != replicas || !equality.Semantic.DeepEqual(stable.Template(), template) {
+		patch := client.MergeFrom(stable.Object().DeepCopyObject().(client.Object))
+		*stable.Template() = *template
+		stable.SetReplicas(replicas)
+		if err := r.Patch(ctx, stable.Object(), patch); err != nil {
+			log.Error(err, "Failed to rebase managed stable workload", "name", pd.Status.StableDeployment)
+			return ctrl.Result{}, err
+		}
+		log.Info("Rebased managed stable workload onto the promoted template",
+			"name", pd.Status.StableDeployment, "replicas", replicas)
+	}
+	if !stable.Available() {
+		log.Info("Waiting for rebased stable workload to become available", "name", pd.Status.StableDeployment)
+		return ctrl.Result{RequeueAfter: readinessPollInterval}, nil
+	}
+
+	// Step 2: Pin the stable selectors to the new stable revision
+	if pd.Status.StableSelector != nil {
+		key, revision, err := stable.RevisionLabel(ctx, r)
+		if err != nil {
+			log.Info("Waiting for rebased stable revision", "reason", err.Error())
+			return ctrl.Result{RequeueAfter: readinessPollInterval}, nil
+		}
+		pd.Status.StableSelector[key] = revision
+	}
+	ready, err := r.syncVersionServices(ctx, pd)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !ready {
+		return ctrl.Result{RequeueAfter: readinessPollInterval}, nil
+	}
+
+	// Step 3: Send the traffic back to stable at full size and scale the new version down
+	pd.Status.StableReplicas = &replicas
+	if err := strategy.Abort(ctx, pd); err != nil {
+		log.Error(err, "Failed to move traffic to the rebased stable workload")
+		return ctrl.Result{}, err
+	}
+	log.Info("Managed stable workload serves the promoted version", "name", pd.Status.StableDeployment)
+	return ctrl.Result{Requeue: true}, r.updateStatus(ctx, pd)
+}
+
+// handleGitOpsFinished keeps the managed workloads in step with Git once a rollout has finished:
+// a completed rollout hands its new version over to <target>-stable, then a new pod template
+// on the target starts the next rollout
+func (r *ProgressiveDeploymentReconciler) handleGitOpsFinished(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (ctrl.Result, error) {
+	strategy, err := r.strategyFor(pd)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if pd.Status.Phase == "Completed" {
+		// The template is compared once the rebase wrote the status and the reconcile started over
+		result, err := r.rebaseManagedStable(ctx, pd, strategy)
+		if err != nil || !result.IsZero() {
+			return result, err
+		}
+	}
+
+	// A rollout that never started has no template to compare with
+	if pd.Status.ObservedTemplateHash == "" {
+		return ctrl.Result{}, nil
+	}
+	source, err := r.getSourceWorkload(ctx, pd)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if computeTemplateHash(source.Template()) == pd.Status.ObservedTemplateHash {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{}, r.restartForTemplate(ctx, pd, strategy, source)
+}
+
+// setTargetScaledDownCondition reports whether the target still runs replicas next to the
+// managed workloads. Scaling it down is left to Git
+func setTargetScaledDownCondition(pd *appsv1alpha1.ProgressiveDeployment, source Workload) {
+	condition := metav1.Condition{
+		Type:               appsv1alpha1.ConditionTargetScaledDown,
+		Status:             metav1.ConditionTrue,
+		Reason:             "ScaledDown",
+		Message:            fmt.Sprintf("%s runs no replicas, %s serves the stable version", source.Object().GetName(), pd.Status.StableDeployment),
+		ObservedGeneration: pd.Generation,
+	}
+	if source.Replicas() > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Running"
+		condition.Message = fmt.Sprintf("%s still runs %d replicas, set replicas: 0 and the %s annotation in Git",
+			source.Object().GetName(), source.Replicas(), replicasAnnotation)
+	}
+	meta.SetStatusCondition(&pd.Status.Conditions, condition)
+}
+
+// rolloutHealth maps the rollout's phase to an Argo CD health status and message
+func rolloutHealth(pd *appsv1alpha1.ProgressiveDeployment) (string, string) {
+	switch pd.Status.Phase {
+	case "Completed":
+		return "Healthy", "Rollout completed"
+	case "RolledBack":
+		return "Degraded", fmt.Sprintf("Rolled back: %s", pd.Status.RollbackReason)
+	case "Failed":
+		return "Degraded", "Rollout failed"
+	}
+	if meta.IsStatusConditionTrue(pd.Status.Conditions, appsv1alpha1.ConditionOutsideWindow) {
+		return "Suspended", meta.FindStatusCondition(pd.Status.Conditions, appsv1alpha1.ConditionOutsideWindow).Message
+	}
+	if pd.Status.Phase == "RollingBack" {
+		return "Progressing", "Rolling back to the stable version"
+	}
+	return "Progressing", fmt.Sprintf("Step %d at %d%%", pd.Status.CurrentStep, pd.Status.CanaryPercentage)
+}
+
+// syncHealthAnnotations mirrors the rollout's health into annotations an Argo CD health
+// check can read (GitOps mode only). It reports whether the annotations were patched
+func (r *ProgressiveDeploymentReconciler) syncHealthAnnotations(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (bool, error) {
+	if !pd.Spec.GitOps || pd.Status.Phase == "" {
+		return false, nil
+	}
+
+	status, message := rolloutHealth(pd)
+	annotations := pd.GetAnnotations()
+	if annotations[healthStatusAnnotation] == status && annotations[healthMessageAnnotation] == message {
+		return false, nil
+	}
+
+	patch := client.MergeFrom(pd.DeepCopy())
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[healthStatusAnnotation] = status
+	annotations[healthMessageAnnotation] = message
+	pd.SetAnnotations(annotations)
+	return true, r.Patch(ctx, pd, patch)
+}
@@ -0,0 +1,257 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+var _ = Describe("GitOps mode", func() {
+	ctx := context.Background()
+
+	DescribeTable("sizing the managed workloads",
+		func(replicas int32, annotation string, expected int32, fails bool) {
+			deployment := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Replicas: ptr.To(replicas)}}
+			if annotation != "" {
+				deployment.Annotations = map[string]string{replicasAnnotation: annotation}
+			}
+			size, err := desiredReplicas(&deploymentWorkload{deployment})
+			if fails {
+				Expect(err).To(HaveOccurred())
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(size).To(Equal(expected))
+		},
+		Entry("reads the annotation", int32(0), "5", int32(5), false),
+		Entry("falls back to the replicas not yet scaled down", int32(3), "", int32(3), false),
+		Entry("needs the annotation at zero replicas", int32(0), "", int32(0), true),
+		Entry("rejects a size that is not a positive number", int32(0), "many", int32(0), true),
+	)
+
+	DescribeTable("mapping the rollout to Argo CD health",
+		func(phase string, expected string) {
+			pd := &appsv1alpha1.ProgressiveDeployment{Status: appsv1alpha1.ProgressiveDeploymentStatus{Phase: phase}}
+			status, _ := rolloutHealth(pd)
+			Expect(status).To(Equal(expected))
+		},
+		Entry("while analyzing", "Analyzing", "Progressing"),
+		Entry("once completed", "Completed", "Healthy"),
+		Entry("once rolled back", "RolledBack", "Degraded"),
+		Entry("once failed", "Failed", "Degraded"),
+	)
+
+	It("should report a rollout held by its schedule as suspended", func() {
+		pd := &appsv1alpha1.ProgressiveDeployment{Status: appsv1alpha1.ProgressiveDeploymentStatus{Phase: "Analyzing"}}
+		meta.SetStatusCondition(&pd.Status.Conditions, metav1.Condition{
+			Type: appsv1alpha1.ConditionOutsideWindow, Status: metav1.ConditionTrue, Reason: "OutsideWindow", Message: "Holding step 1",
+		})
+		status, message := rolloutHealth(pd)
+		Expect(status).To(Equal("Suspended"))
+		Expect(message).To(Equal("Holding step 1"))
+	})
+
+	Context("When the target is reconciled by a GitOps tool", func() {
+		var pd *appsv1alpha1.ProgressiveDeployment
+		var source *appsv1.Deployment
+
+		markAvailable := func(name string) {
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: name}, deployment)).To(Succeed())
+			replicas := *deployment.Spec.Replicas
+			deployment.Status = appsv1.DeploymentStatus{
+				ObservedGeneration: deployment.Generation, Replicas: replicas, UpdatedReplicas: replicas, AvailableReplicas: replicas,
+			}
+			Expect(k8sClient.Status().Update(ctx, deployment)).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			labels := map[string]string{"app": "shop"}
+			source = &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "shop",
+					Namespace:   "default",
+					Annotations: map[string]string{replicasAnnotation: "4"},
+				},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: ptr.To[int32](0),
+					Selector: &metav1.LabelSelector{MatchLabels: labels},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: labels},
+						Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "shop", Image: "shop:1.0"}}},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, source)).To(Succeed())
+
+			pd = &appsv1alpha1.ProgressiveDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "gitops-test", Namespace: "default"},
+				Spec: appsv1alpha1.ProgressiveDeploymentSpec{
+					TargetDeployment: "shop",
+					GitOps:           true,
+					CanarySteps:      []int{50, 100},
+				},
+			}
+			Expect(k8sClient.Create(ctx, pd)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.Delete(ctx, pd)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, source)).To(Succeed())
+			for _, name := range []string{"shop-stable", "shop-canary"} {
+				managed := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, managed))).To(Succeed())
+			}
+		})
+
+		It("should split traffic between managed workloads and never write to the target", func() {
+			reconciler := &ProgressiveDeploymentReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+			strategy := &canaryStrategy{reconciler}
+
+			_, ready, err := reconciler.ensureManagedStable(ctx, pd)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ready).To(BeFalse())
+			markAvailable("shop-stable")
+			stable, ready, err := reconciler.ensureManagedStable(ctx, pd)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ready).To(BeTrue())
+			Expect(pd.Status.StableDeployment).To(Equal("shop-stable"))
+			Expect(stable.Replicas()).To(Equal(int32(4)))
+			Expect(stable.Object().GetAnnotations()).NotTo(HaveKey(replicasAnnotation))
+			Expect(meta.IsStatusConditionTrue(pd.Status.Conditions, appsv1alpha1.ConditionTargetScaledDown)).To(BeTrue())
+
+			// Git moves on to a new image, the canary is cloned from it
+			source.Spec.Template.Spec.Containers[0].Image = "shop:2.0"
+			Expect(k8sClient.Update(ctx, source)).To(Succeed())
+			resourceVersion := source.ResourceVersion
+
+			_, err = strategy.Init(ctx, pd, stable)
+			Expect(err).NotTo(HaveOccurred())
+			pd.Status.CanaryPercentage = 50
+			Expect(strategy.ApplyStep(ctx, pd)).To(BeTrue())
+
+			canary := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "shop-canary"}, canary)).To(Succeed())
+			Expect(canary.Spec.Template.Spec.Containers[0].Image).To(Equal("shop:2.0"))
+			Expect(*canary.Spec.Replicas).To(Equal(int32(2)))
+
+			managedStable := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "shop-stable"}, managedStable)).To(Succeed())
+			Expect(managedStable.Spec.Template.Spec.Containers[0].Image).To(Equal("shop:1.0"))
+			Expect(*managedStable.Spec.Replicas).To(Equal(int32(2)))
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(source), source)).To(Succeed())
+			Expect(source.ResourceVersion).To(Equal(resourceVersion))
+		})
+
+		It("should rebase stable onto the promoted version and start the next rollout from Git", func() {
+			reconciler := &ProgressiveDeploymentReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+			strategy := &canaryStrategy{reconciler}
+			getDeployment := func(name string) *appsv1.Deployment {
+				deployment := &appsv1.Deployment{}
+				Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: name}, deployment)).To(Succeed())
+				return deployment
+			}
+
+			_, _, err := reconciler.ensureManagedStable(ctx, pd)
+			Expect(err).NotTo(HaveOccurred())
+			markAvailable("shop-stable")
+			stable, _, err := reconciler.ensureManagedStable(ctx, pd)
+			Expect(err).NotTo(HaveOccurred())
+
+			// The first rollout takes shop:2.0 from Git to all the traffic
+			source.Spec.Template.Spec.Containers[0].Image = "shop:2.0"
+			Expect(k8sClient.Update(ctx, source)).To(Succeed())
+			_, err = strategy.Init(ctx, pd, stable)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reconciler.recordObservedSpec(ctx, pd, strategy.Steps(pd))).To(Succeed())
+			pd.Status.CanaryPercentage = 100
+			Expect(strategy.ApplyStep(ctx, pd)).To(BeTrue())
+			Expect(*getDeployment("shop-stable").Spec.Replicas).To(BeZero())
+			markAvailable("shop-stable")
+			pd.Status.Phase = "Completed"
+			Expect(k8sClient.Status().Update(ctx, pd)).To(Succeed())
+
+			// Stable takes over the promoted template before the canary is scaled down
+			result, err := reconciler.handleGitOpsFinished(ctx, pd)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(readinessPollInterval))
+			managedStable := getDeployment("shop-stable")
+			Expect(managedStable.Spec.Template.Spec.Containers[0].Image).To(Equal("shop:2.0"))
+			Expect(managedStable.Spec.Template.Labels).To(HaveKeyWithValue(trackLabelKey(pd), stableRole))
+			Expect(*managedStable.Spec.Replicas).To(Equal(int32(4)))
+			Expect(*getDeployment("shop-canary").Spec.Replicas).To(Equal(int32(4)))
+
+			markAvailable("shop-stable")
+			result, err = reconciler.handleGitOpsFinished(ctx, pd)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeZero())
+			Expect(result.Requeue).To(BeTrue())
+			Expect(*getDeployment("shop-stable").Spec.Replicas).To(Equal(int32(4)))
+			Expect(*getDeployment("shop-canary").Spec.Replicas).To(BeZero())
+			Expect(pd.Status.Phase).To(Equal("Completed"))
+
+			// Git moves on again, the second rollout clones its canary from the new template
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(source), source)).To(Succeed())
+			source.Spec.Template.Spec.Containers[0].Image = "shop:3.0"
+			Expect(k8sClient.Update(ctx, source)).To(Succeed())
+			_, err = reconciler.handleGitOpsFinished(ctx, pd)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pd.Status.Phase).To(Equal("Initializing"))
+			err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "shop-canary"}, &appsv1.Deployment{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			stable, ready, err := reconciler.ensureManagedStable(ctx, pd)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ready).To(BeTrue())
+			_, err = strategy.Init(ctx, pd, stable)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(getDeployment("shop-canary").Spec.Template.Spec.Containers[0].Image).To(Equal("shop:3.0"))
+			Expect(getDeployment("shop-stable").Spec.Template.Spec.Containers[0].Image).To(Equal("shop:2.0"))
+		})
+
+		It("should annotate the ProgressiveDeployment with its health", func() {
+			reconciler := &ProgressiveDeploymentReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+			pd.Status.Phase = "Completed"
+			annotated, err := reconciler.syncHealthAnnotations(ctx, pd)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(annotated).To(BeTrue())
+
+			stored := &appsv1alpha1.ProgressiveDeployment{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pd), stored)).To(Succeed())
+			Expect(stored.Annotations).To(HaveKeyWithValue(healthStatusAnnotation, "Healthy"))
+			Expect(stored.Annotations).To(HaveKeyWithValue(healthMessageAnnotation, "Rollout completed"))
+
+			// Annotations already in step are left alone
+			annotated, err = reconciler.syncHealthAnnotations(ctx, pd)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(annotated).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,339 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/rand"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+// defaultRevisionHistoryLimit is used when spec.revisionHistoryLimit is not set
+const defaultRevisionHistoryLimit = 10
+
+// reasonRollbackToFailed is the reason of the event reporting a spec.rollbackTo that cannot be restored
+const reasonRollbackToFailed = "RollbackToFailed"
+
+// computeTemplateHash returns a short, stable hash of a pod template
+func computeTemplateHash(template *corev1.PodTemplateSpec) string {
+	return computeHash(template)
+}
+
+// computeHash returns a short hash of the JSON encoding of value
+func computeHash(value any) string {
+	hasher := fnv.New32a()
+	data, _ := json.Marshal(value)
+	_, _ = hasher.Write(data)
+	return rand.SafeEncodeString(fmt.Sprint(hasher.Sum32()))
+}
+
+// templateImages returns the comma-separated container images of a pod template
+func templateImages(template *corev1.PodTemplateSpec) string {
+	images := make([]string, 0, len(template.Spec.Containers))
+	for _, container := range template.Spec.Containers {
+		images = append(images, container.Image)
+	}
+	return strings.Join(images, ",")
+}
+
+// revisionName returns the name of the ControllerRevision holding a template
+func revisionName(pd *appsv1alpha1.ProgressiveDeployment, templateHash string) string {
+	return fmt.Sprintf("%s-%s", pd.Name, templateHash)
+}
+
+// historyLimit returns how many revisions are kept in status.history
+func historyLimit(pd *appsv1alpha1.ProgressiveDeployment) int {
+	if pd.Spec.RevisionHistoryLimit == nil {
+		return defaultRevisionHistoryLimit
+	}
+	return int(*pd.Spec.RevisionHistoryLimit)
+}
+
+// nextRevision returns the revision number of the next history record
+func nextRevision(pd *appsv1alpha1.ProgressiveDeployment) int64 {
+	if len(pd.Status.History) == 0 {
+		return 1
+	}
+	return pd.Status.History[len(pd.Status.History)-1].Revision + 1
+}
+
+// recordStepResult appends the analysis outcome of the current step to status.stepResults
+func recordStepResult(pd *appsv1alpha1.ProgressiveDeployment, healthy bool, metrics map[string]float64, analysisErr error) {
+	result := appsv1alpha1.StepResult{
+		Step:             pd.Status.CurrentStep,
+		CanaryPercentage: pd.Status.CanaryPercentage,
+		Healthy:          healthy,
+		Metrics:          metrics,
+		AnalyzedAt:       metav1.Now(),
+	}
+	if analysisErr != nil {
+		result.Message = analysisErr.Error()
+	}
+	pd.Status.StepResults = append(pd.Status.StepResults, result)
+}
+
+// recordRevision appends the finished rollout attempt to status.history and keeps its
+// pod template in a ControllerRevision, so it can be rolled out again later
+func (r *ProgressiveDeploymentReconciler) recordRevision(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) error {
+	log := logf.FromContext(ctx)
+
+	now := metav1.Now()
+	record := appsv1alpha1.RolloutRevision{
+		Revision:       nextRevision(pd),
+		StartedAt:      pd.Status.StartedAt,
+		FinishedAt:     &now,
+		Phase:          pd.Status.Phase,
+		Steps:          pd.Status.StepResults,
+		RollbackReason: pd.Status.RollbackReason,
+	}
+
+	var newVersion Workload
+	var err error = errors.NewNotFound(appsv1.Resource("deployments"), pd.Status.CanaryDeployment)
+	if pd.Status.CanaryDeployment != "" {
+		newVersion, err = r.getWorkload(ctx, pd, pd.Status.CanaryDeployment)
+	}
+	switch {
+	case errors.IsNotFound(err):
+		log.Info("New version workload not found, recording revision without template", "name", pd.Status.CanaryDeployment)
+	case err != nil:
+		return err
+	default:
+		record.TemplateHash = computeTemplateHash(newVersion.Template())
+		record.Image = templateImages(newVersion.Template())
+		if err := r.saveTemplate(ctx, pd, record, newVersion.Template()); err != nil {
+			log.Error(err, "Failed to save revision template")
+			return err
+		}
+	}
+
+	pd.Status.History = append(pd.Status.History, record)
+	if limit := historyLimit(pd); len(pd.Status.History) > limit {
+		pd.Status.History = pd.Status.History[len(pd.Status.History)-limit:]
+	}
+
+	// The attempt now lives in the history
+	pd.Status.StartedAt = nil
+	pd.Status.StepResults = nil
+
+	log.Info("Recorded rollout revision",
+		"revision", record.Revision,
+		"phase", record.Phase,
+		"templateHash", record.TemplateHash)
+
+	return r.pruneTemplates(ctx, pd)
+}
+
+// saveTemplate stores a pod template in a ControllerRevision owned by the ProgressiveDeployment
+func (r *ProgressiveDeploymentReconciler) saveTemplate(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, record appsv1alpha1.RolloutRevision, template *corev1.PodTemplateSpec) error {
+	data, err := json.Marshal(template)
+	if err != nil {
+		return err
+	}
+
+	revision := &appsv1.ControllerRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      revisionName(pd, record.TemplateHash),
+			Namespace: pd.Namespace,
+			Labels: map[string]string{
+				"progressive-deployment": pd.Name,
+			},
+		},
+		Data:     runtime.RawExtension{Raw: data},
+		Revision: record.Revision,
+	}
+	if err := ctrl.SetControllerReference(pd, revision, r.Scheme); err != nil {
+		return err
+	}
+
+	err = r.Create(ctx, revision)
+	if !errors.IsAlreadyExists(err) {
+		return err
+	}
+
+	// The same template was rolled out before, move it to the latest revision
+	existing := &appsv1.ControllerRevision{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(revision), existing); err != nil {
+		return err
+	}
+	patch := client.MergeFrom(existing.DeepCopy())
+	existing.Revision = record.Revision
+	return r.Patch(ctx, existing, patch)
+}
+
+// pruneTemplates deletes the ControllerRevisions no longer referenced by status.history
+func (r *ProgressiveDeploymentReconciler) pruneTemplates(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) error {
+	referenced := make(map[string]bool, len(pd.Status.History))
+	for _, record := range pd.Status.History {
+		referenced[revisionName(pd, record.TemplateHash)] = true
+	}
+
+	revisions := &appsv1.ControllerRevisionList{}
+	if err := r.List(ctx, revisions,
+		client.InNamespace(pd.Namespace),
+		client.MatchingLabels{"progressive-deployment": pd.Name}); err != nil {
+		return err
+	}
+
+	for i := range revisions.Items {
+		revision := &revisions.Items[i]
+		if referenced[revision.Name] || !metav1.IsControlledBy(revision, pd) {
+			continue
+		}
+		if err := r.Delete(ctx, revision); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTemplate returns the pod template recorded for a revision in status.history
+func (r *ProgressiveDeploymentReconciler) loadTemplate(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, revision int64) (*corev1.PodTemplateSpec, error) {
+	var record *appsv1alpha1.RolloutRevision
+	for i := range pd.Status.History {
+		if pd.Status.History[i].Revision == revision {
+			record = &pd.Status.History[i]
+		}
+	}
+	if record == nil {
+		return nil, fmt.Errorf("revision %d not found in history", revision)
+	}
+	if record.TemplateHash == "" {
+		return nil, fmt.Errorf("revision %d has no recorded template", revision)
+	}
+
+	stored := &appsv1.ControllerRevision{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: pd.Namespace, Name: revisionName(pd, record.TemplateHash)}, stored); err != nil {
+		return nil, err
+	}
+
+	template := &corev1.PodTemplateSpec{}
+	if err := json.Unmarshal(stored.Data.Raw, template); err != nil {
+		return nil, fmt.Errorf("failed to decode template of revision %d: %w", revision, err)
+	}
+	return template, nil
+}
+
+// resetRollout clears the status of the previous attempt so the state machine starts again at step
+func resetRollout(pd *appsv1alpha1.ProgressiveDeployment, step int) {
+	now := metav1.Now()
+	pd.Status.Phase = "Initializing"
+	pd.Status.CurrentStep = step
+	pd.Status.CanaryPercentage = 0
+	pd.Status.EffectivePercentage = 0
+	pd.Status.HealthStatus = "Unknown"
+	pd.Status.Metrics = nil
+	pd.Status.LastAnalysisTime = nil
+	pd.Status.StableSelector = nil
+	pd.Status.SwitchedAt = nil
+	pd.Status.StartedAt = &now
+	pd.Status.StepResults = nil
+	pd.Status.InconclusiveAnalyses = 0
+	pd.Status.RollbackReason = ""
+}
+
+// rollbackToPending reports whether spec.rollbackTo asks for a rollback the controller has not acted on yet
+func rollbackToPending(pd *appsv1alpha1.ProgressiveDeployment) bool {
+	if pd.Spec.RollbackTo == nil {
+		return false
+	}
+	return !equality.Semantic.DeepEqual(pd.Spec.RollbackTo, pd.Status.RolledBackTo)
+}
+
+// handleRollbackTo restarts a finished rollout from the template of a past revision.
+// The request is recorded in status.rolledBackTo, spec is left to its owner
+func (r *ProgressiveDeploymentReconciler) handleRollbackTo(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+	revision := pd.Spec.RollbackTo.Revision
+	log.Info("Restarting rollout from past revision", "revision", revision)
+
+	template, err := r.loadTemplate(ctx, pd, revision)
+	if err != nil {
+		// The revision cannot be restored, reject the request instead of retrying forever
+		return r.rejectRollbackTo(ctx, pd, err)
+	}
+
+	// Put the past template back on the workload running the new version
+	newVersion, err := r.getWorkload(ctx, pd, pd.Status.CanaryDeployment)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return r.rejectRollbackTo(ctx, pd, fmt.Errorf("new version workload %q not found", pd.Status.CanaryDeployment))
+		}
+		log.Error(err, "Failed to get new version workload", "name", pd.Status.CanaryDeployment)
+		return ctrl.Result{}, err
+	}
+	patch := client.MergeFrom(newVersion.Object().DeepCopyObject().(client.Object))
+	*newVersion.Template() = *template
+	if err := r.Patch(ctx, newVersion.Object(), patch); err != nil {
+		log.Error(err, "Failed to restore template", "revision", revision)
+		return ctrl.Result{}, err
+	}
+
+	// Record the request with the restart, so the restarted rollout is not restarted again
+	resetRollout(pd, 0)
+	pd.Status.RolledBackTo = pd.Spec.RollbackTo.DeepCopy()
+	meta.SetStatusCondition(&pd.Status.Conditions, metav1.Condition{
+		Type:               appsv1alpha1.ConditionRevisionRestored,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Restored",
+		Message:            fmt.Sprintf("Restarted the rollout from revision %d", revision),
+		ObservedGeneration: pd.Generation,
+	})
+	if err := r.updateStatus(ctx, pd); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	log.Info("Rollout restarted", "revision", revision, "templateHash", computeTemplateHash(template))
+	return ctrl.Result{}, nil
+}
+
+// rejectRollbackTo records a spec.rollbackTo that cannot be restored, so it is reported once
+// and not retried until a new request is made
+func (r *ProgressiveDeploymentReconciler) rejectRollbackTo(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, err error) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+	revision := pd.Spec.RollbackTo.Revision
+	log.Error(err, "Cannot roll back to revision", "revision", revision)
+
+	pd.Status.RolledBackTo = pd.Spec.RollbackTo.DeepCopy()
+	meta.SetStatusCondition(&pd.Status.Conditions, metav1.Condition{
+		Type:               appsv1alpha1.ConditionRevisionRestored,
+		Status:             metav1.ConditionFalse,
+		Reason:             "RevisionUnavailable",
+		Message:            fmt.Sprintf("Cannot roll back to revision %d: %v", revision, err),
+		ObservedGeneration: pd.Generation,
+	})
+	if err := r.updateStatus(ctx, pd); err != nil {
+		return ctrl.Result{}, err
+	}
+	r.event(pd, corev1.EventTypeWarning, reasonRollbackToFailed, "Cannot roll back to revision %d: %v", revision, err)
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+var _ = Describe("Rollout history", func() {
+	const (
+		resourceName = "history-test"
+		canaryName   = "history-app-canary"
+	)
+
+	ctx := context.Background()
+	var (
+		pd         *appsv1alpha1.ProgressiveDeployment
+		canary     *appsv1.Deployment
+		reconciler *ProgressiveDeploymentReconciler
+	)
+
+	BeforeEach(func() {
+		pd = &appsv1alpha1.ProgressiveDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"},
+			Spec: appsv1alpha1.ProgressiveDeploymentSpec{
+				TargetDeployment:     "history-app",
+				CanarySteps:          []int{50, 100},
+				RevisionHistoryLimit: ptr.To[int32](2),
+			},
+		}
+		Expect(k8sClient.Create(ctx, pd)).To(Succeed())
+
+		labels := map[string]string{"app": "history-app", "version": "canary"}
+		canary = &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: canaryName, Namespace: "default"},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: ptr.To[int32](0),
+				Selector: &metav1.LabelSelector{MatchLabels: labels},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: labels},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: "app", Image: "demo:v1"}},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, canary)).To(Succeed())
+
+		pd.Status.CanaryDeployment = canaryName
+		reconciler = &ProgressiveDeploymentReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+	})
+
+	AfterEach(func() {
+		Expect(k8sClient.Delete(ctx, canary)).To(Succeed())
+		Expect(k8sClient.DeleteAllOf(ctx, &appsv1.ControllerRevision{},
+			client.InNamespace("default"),
+			client.MatchingLabels{"progressive-deployment": resourceName})).To(Succeed())
+		Expect(k8sClient.Delete(ctx, pd)).To(Succeed())
+	})
+
+	rollOut := func(image, phase string) {
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(canary), canary)).To(Succeed())
+		canary.Spec.Template.Spec.Containers[0].Image = image
+		Expect(k8sClient.Update(ctx, canary)).To(Succeed())
+
+		pd.Status.Phase = phase
+		recordStepResult(pd, phase == "Completed", map[string]float64{"errorRate": 0.01}, nil)
+		Expect(reconciler.recordRevision(ctx, pd)).To(Succeed())
+	}
+
+	It("should keep a bounded history with the template of every revision", func() {
+		rollOut("demo:v1", "Completed")
+		rollOut("demo:v2", "RolledBack")
+		rollOut("demo:v3", "Completed")
+
+		Expect(pd.Status.History).To(HaveLen(2))
+		Expect(pd.Status.History[0].Revision).To(Equal(int64(2)))
+		Expect(pd.Status.History[0].Image).To(Equal("demo:v2"))
+		Expect(pd.Status.History[0].Phase).To(Equal("RolledBack"))
+		Expect(pd.Status.History[0].Steps).To(HaveLen(1))
+		Expect(pd.Status.History[1].Revision).To(Equal(int64(3)))
+		Expect(pd.Status.StepResults).To(BeEmpty())
+
+		revisions := &appsv1.ControllerRevisionList{}
+		Expect(k8sClient.List(ctx, revisions,
+			client.InNamespace("default"),
+			client.MatchingLabels{"progressive-deployment": resourceName})).To(Succeed())
+		Expect(revisions.Items).To(HaveLen(2))
+
+		template, err := reconciler.loadTemplate(ctx, pd, 2)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(template.Spec.Containers[0].Image).To(Equal("demo:v2"))
+
+		_, err = reconciler.loadTemplate(ctx, pd, 1)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should restart from step 0 with the template of a past revision", func() {
+		rollOut("demo:v1", "Completed")
+		rollOut("demo:v2", "RolledBack")
+		Expect(k8sClient.Status().Update(ctx, pd)).To(Succeed())
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pd), pd)).To(Succeed())
+		pd.Spec.RollbackTo = &appsv1alpha1.RollbackConfig{Revision: 1}
+		Expect(k8sClient.Update(ctx, pd)).To(Succeed())
+
+		Expect(rollbackToPending(pd)).To(BeTrue())
+		_, err := reconciler.handleRollbackTo(ctx, pd)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pd), pd)).To(Succeed())
+		Expect(pd.Spec.RollbackTo).NotTo(BeNil())
+		Expect(pd.Status.RolledBackTo).To(Equal(pd.Spec.RollbackTo))
+		Expect(rollbackToPending(pd)).To(BeFalse())
+		Expect(meta.IsStatusConditionTrue(pd.Status.Conditions, appsv1alpha1.ConditionRevisionRestored)).To(BeTrue())
+		Expect(pd.Status.Phase).To(Equal("Initializing"))
+		Expect(pd.Status.CurrentStep).To(Equal(0))
+		Expect(pd.Status.History).To(HaveLen(2))
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(canary), canary)).To(Succeed())
+		Expect(canary.Spec.Template.Spec.Containers[0].Image).To(Equal("demo:v1"))
+	})
+
+	It("should reject a revision that cannot be restored once", func() {
+		rollOut("demo:v1", "Completed")
+		Expect(k8sClient.Status().Update(ctx, pd)).To(Succeed())
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pd), pd)).To(Succeed())
+		pd.Spec.RollbackTo = &appsv1alpha1.RollbackConfig{Revision: 7}
+		Expect(k8sClient.Update(ctx, pd)).To(Succeed())
+
+		recorder := record.NewFakeRecorder(10)
+		reconciler.Recorder = recorder
+		_, err := reconciler.handleRollbackTo(ctx, pd)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pd), pd)).To(Succeed())
+		Expect(pd.Status.Phase).To(Equal("Completed"))
+		Expect(rollbackToPending(pd)).To(BeFalse())
+		condition := meta.FindStatusCondition(pd.Status.Conditions, appsv1alpha1.ConditionRevisionRestored)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Message).To(ContainSubstring("revision 7 not found"))
+		Expect(recorder.Events).To(Receive(ContainSubstring(reasonRollbackToFailed)))
+
+		// A new request for a revision is acted on again
+		now := metav1.NowMicro()
+		pd.Spec.RollbackTo = &appsv1alpha1.RollbackConfig{Revision: 7, RequestedAt: &now}
+		Expect(rollbackToPending(pd)).To(BeTrue())
+	})
+})
@@ -0,0 +1,239 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+// The HorizontalPodAutoscaler of the target keeps scaling the stable workload during a rollout.
+// Instead of fighting it, the rollout splits its bounds: the target's HPA keeps the stable
+// share and a sibling HPA, named after the new version workload, scales the canary share.
+// On completion the sibling takes over the original bounds; the target is at zero replicas
+// by then, which disables its HPA. On rollback the sibling is deleted. The target's HPA gets
+// its original bounds back in both cases.
+
+// findAutoscaler returns the HorizontalPodAutoscaler scaling the target, or nil if there is none
+func (r *ProgressiveDeploymentReconciler) findAutoscaler(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+	ref := targetRef(pd)
+
+	autoscalers := &autoscalingv2.HorizontalPodAutoscalerList{}
+	if err := r.List(ctx, autoscalers, client.InNamespace(pd.Namespace)); err != nil {
+		return nil, err
+	}
+	for i := range autoscalers.Items {
+		target := autoscalers.Items[i].Spec.ScaleTargetRef
+		if target.Kind == ref.Kind && target.Name == ref.Name {
+			return &autoscalers.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// trackAutoscaler records the bounds of the target's HPA in status.autoscaler, once per rollout
+func (r *ProgressiveDeploymentReconciler) trackAutoscaler(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) error {
+	// In GitOps mode an autoscaler on the target belongs to Git and scales a workload kept at zero
+	if pd.Status.Autoscaler != nil || pd.Spec.GitOps {
+		return nil
+	}
+
+	hpa, err := r.findAutoscaler(ctx, pd)
+	if err != nil || hpa == nil {
+		return err
+	}
+
+	minReplicas := int32(1)
+	if hpa.Spec.MinReplicas != nil {
+		minReplicas = *hpa.Spec.MinReplicas
+	}
+	pd.Status.Autoscaler = &appsv1alpha1.AutoscalerStatus{
+		Name:        hpa.Name,
+		MinReplicas: minReplicas,
+		MaxReplicas: hpa.Spec.MaxReplicas,
+	}
+
+	logf.FromContext(ctx).Info("Target is autoscaled, splitting its bounds during the rollout",
+		"hpa", hpa.Name, "minReplicas", minReplicas, "maxReplicas", hpa.Spec.MaxReplicas)
+	return nil
+}
+
+// splitBounds divides HPA bounds between stable and canary by canary percentage.
+// An HPA needs at least one replica, so neither side drops below one
+func splitBounds(minReplicas, maxReplicas int32, canaryPercentage int) (stableMin, stableMax, canaryMin, canaryMax int32) {
+	stableMin, canaryMin = calculateReplicaDistribution(int(minReplicas), canaryPercentage)
+	stableMax, canaryMax = calculateReplicaDistribution(int(maxReplicas), canaryPercentage)
+
+	stableMin, canaryMin = max(stableMin, 1), max(canaryMin, 1)
+	stableMax, canaryMax = max(stableMax, stableMin), max(canaryMax, canaryMin)
+	return stableMin, stableMax, canaryMin, canaryMax
+}
+
+// setAutoscalerBounds updates the bounds of an HPA if they changed
+func (r *ProgressiveDeploymentReconciler) setAutoscalerBounds(ctx context.Context, hpa *autoscalingv2.HorizontalPodAutoscaler, minReplicas, maxReplicas int32) error {
+	if hpa.Spec.MinReplicas != nil && *hpa.Spec.MinReplicas == minReplicas && hpa.Spec.MaxReplicas == maxReplicas {
+		return nil
+	}
+	patch := client.MergeFrom(hpa.DeepCopy())
+	hpa.Spec.MinReplicas = &minReplicas
+	hpa.Spec.MaxReplicas = maxReplicas
+	return r.Patch(ctx, hpa, patch)
+}
+
+// getAutoscaler fetches an HPA in the ProgressiveDeployment namespace
+func (r *ProgressiveDeploymentReconciler) getAutoscaler(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, name string) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: pd.Namespace, Name: name}, hpa); err != nil {
+		return nil, err
+	}
+	return hpa, nil
+}
+
+// ensureSiblingAutoscaler creates or updates the HPA scaling the new version workload,
+// with the metrics and behavior of the target's HPA
+func (r *ProgressiveDeploymentReconciler) ensureSiblingAutoscaler(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, original *autoscalingv2.HorizontalPodAutoscaler, minReplicas, maxReplicas int32) error {
+	sibling, err := r.getAutoscaler(ctx, pd, pd.Status.CanaryDeployment)
+	if err == nil {
+		return r.setAutoscalerBounds(ctx, sibling, minReplicas, maxReplicas)
+	}
+	if !errors.IsNotFound(err) {
+		return err
+	}
+
+	sibling = &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pd.Status.CanaryDeployment,
+			Namespace: pd.Namespace,
+			Labels: map[string]string{
+				"progressive-deployment": pd.Name,
+			},
+		},
+		Spec: *original.Spec.DeepCopy(),
+	}
+	sibling.Spec.ScaleTargetRef.Name = pd.Status.CanaryDeployment
+	sibling.Spec.MinReplicas = &minReplicas
+	sibling.Spec.MaxReplicas = maxReplicas
+
+	if err := ctrl.SetControllerReference(pd, sibling, r.Scheme); err != nil {
+		return err
+	}
+	if err := r.Create(ctx, sibling); err != nil {
+		return err
+	}
+
+	logf.FromContext(ctx).Info("Created autoscaler for new version", "hpa", sibling.Name)
+	return nil
+}
+
+// mirrorAutoscaler gives the new version workload an HPA with the original bounds,
+// for strategies that run it at full size next to the stable workload
+func (r *ProgressiveDeploymentReconciler) mirrorAutoscaler(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) error {
+	if pd.Status.Autoscaler == nil {
+		return nil
+	}
+	bounds := pd.Status.Autoscaler
+
+	original, err := r.getAutoscaler(ctx, pd, bounds.Name)
+	if err != nil {
+		return err
+	}
+	return r.ensureSiblingAutoscaler(ctx, pd, original, bounds.MinReplicas, bounds.MaxReplicas)
+}
+
+// splitAutoscaler gives the stable and new version HPAs their share of the original bounds
+func (r *ProgressiveDeploymentReconciler) splitAutoscaler(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, canaryPercentage int) error {
+	if pd.Status.Autoscaler == nil {
+		return nil
+	}
+	log := logf.FromContext(ctx)
+	bounds := pd.Status.Autoscaler
+
+	stableMin, stableMax, canaryMin, canaryMax := splitBounds(bounds.MinReplicas, bounds.MaxReplicas, canaryPercentage)
+
+	original, err := r.getAutoscaler(ctx, pd, bounds.Name)
+	if err != nil {
+		log.Error(err, "Failed to get autoscaler", "hpa", bounds.Name)
+		return err
+	}
+	if err := r.setAutoscalerBounds(ctx, original, stableMin, stableMax); err != nil {
+		log.Error(err, "Failed to update stable autoscaler bounds")
+		return err
+	}
+	if err := r.ensureSiblingAutoscaler(ctx, pd, original, canaryMin, canaryMax); err != nil {
+		log.Error(err, "Failed to update new version autoscaler bounds")
+		return err
+	}
+
+	log.Info("Split autoscaler bounds",
+		"stableMin", stableMin, "stableMax", stableMax,
+		"canaryMin", canaryMin, "canaryMax", canaryMax)
+	return nil
+}
+
+// restoreAutoscaler puts the original bounds back on the target's HPA. The sibling HPA
+// keeps scaling the new version with the original bounds when it was promoted, and is
+// deleted otherwise
+func (r *ProgressiveDeploymentReconciler) restoreAutoscaler(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, promoted bool) error {
+	if pd.Status.Autoscaler == nil {
+		return nil
+	}
+	log := logf.FromContext(ctx)
+	bounds := pd.Status.Autoscaler
+
+	original, err := r.getAutoscaler(ctx, pd, bounds.Name)
+	switch {
+	case errors.IsNotFound(err):
+		log.Info("Autoscaler was deleted, nothing to restore", "hpa", bounds.Name)
+	case err != nil:
+		return err
+	default:
+		if err := r.setAutoscalerBounds(ctx, original, bounds.MinReplicas, bounds.MaxReplicas); err != nil {
+			log.Error(err, "Failed to restore autoscaler bounds")
+			return err
+		}
+		if promoted {
+			if err := r.ensureSiblingAutoscaler(ctx, pd, original, bounds.MinReplicas, bounds.MaxReplicas); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !promoted {
+		sibling, err := r.getAutoscaler(ctx, pd, pd.Status.CanaryDeployment)
+		if client.IgnoreNotFound(err) != nil {
+			return err
+		}
+		if err == nil && metav1.IsControlledBy(sibling, pd) {
+			if err := r.Delete(ctx, sibling); client.IgnoreNotFound(err) != nil {
+				return err
+			}
+		}
+	}
+
+	log.Info("Restored autoscaler bounds", "hpa", bounds.Name, "promoted", promoted,
+		"minReplicas", bounds.MinReplicas, "maxReplicas", bounds.MaxReplicas)
+	pd.Status.Autoscaler = nil
+	return nil
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+var _ = Describe("Autoscaler awareness", func() {
+	DescribeTable("splitting HPA bounds",
+		func(minReplicas, maxReplicas int32, percentage int, stableMin, stableMax, canaryMin, canaryMax int32) {
+			gotStableMin, gotStableMax, gotCanaryMin, gotCanaryMax := splitBounds(minReplicas, maxReplicas, percentage)
+			Expect([]int32{gotStableMin, gotStableMax, gotCanaryMin, gotCanaryMax}).To(Equal([]int32{stableMin, stableMax, canaryMin, canaryMax}))
+		},
+		Entry("25% of 4-20", int32(4), int32(20), 25, int32(3), int32(15), int32(1), int32(5)),
+		Entry("50% of 2-10", int32(2), int32(10), 50, int32(1), int32(5), int32(1), int32(5)),
+		Entry("100% keeps one stable replica", int32(2), int32(10), 100, int32(1), int32(1), int32(2), int32(10)),
+		Entry("small canary share is at least one", int32(1), int32(3), 10, int32(1), int32(2), int32(1), int32(1)),
+	)
+
+	Context("When the target is autoscaled", func() {
+		ctx := context.Background()
+		var pd *appsv1alpha1.ProgressiveDeployment
+		var hpa *autoscalingv2.HorizontalPodAutoscaler
+		var reconciler *ProgressiveDeploymentReconciler
+
+		BeforeEach(func() {
+			hpa = &autoscalingv2.HorizontalPodAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "hpa-app", Namespace: "default"},
+				Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+					ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "hpa-app"},
+					MinReplicas:    ptr.To[int32](4),
+					MaxReplicas:    20,
+				},
+			}
+			Expect(k8sClient.Create(ctx, hpa)).To(Succeed())
+
+			pd = &appsv1alpha1.ProgressiveDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "hpa-test", Namespace: "default"},
+				Spec: appsv1alpha1.ProgressiveDeploymentSpec{
+					TargetDeployment: "hpa-app",
+					CanarySteps:      []int{25, 100},
+				},
+			}
+			Expect(k8sClient.Create(ctx, pd)).To(Succeed())
+			pd.Status.CanaryDeployment = "hpa-app-canary"
+
+			reconciler = &ProgressiveDeploymentReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.Delete(ctx, pd)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, hpa)).To(Succeed())
+			sibling := &autoscalingv2.HorizontalPodAutoscaler{ObjectMeta: metav1.ObjectMeta{Name: "hpa-app-canary", Namespace: "default"}}
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, sibling))).To(Succeed())
+		})
+
+		bounds := func(name string) (int32, int32) {
+			current := &autoscalingv2.HorizontalPodAutoscaler{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: name}, current)).To(Succeed())
+			return *current.Spec.MinReplicas, current.Spec.MaxReplicas
+		}
+
+		It("should split the bounds with a sibling HPA and restore them on rollback", func() {
+			Expect(reconciler.trackAutoscaler(ctx, pd)).To(Succeed())
+			Expect(pd.Status.Autoscaler).To(Equal(&appsv1alpha1.AutoscalerStatus{Name: "hpa-app", MinReplicas: 4, MaxReplicas: 20}))
+
+			Expect(reconciler.splitAutoscaler(ctx, pd, 25)).To(Succeed())
+			minReplicas, maxReplicas := bounds("hpa-app")
+			Expect([]int32{minReplicas, maxReplicas}).To(Equal([]int32{3, 15}))
+			minReplicas, maxReplicas = bounds("hpa-app-canary")
+			Expect([]int32{minReplicas, maxReplicas}).To(Equal([]int32{1, 5}))
+
+			Expect(reconciler.restoreAutoscaler(ctx, pd, false)).To(Succeed())
+			minReplicas, maxReplicas = bounds("hpa-app")
+			Expect([]int32{minReplicas, maxReplicas}).To(Equal([]int32{4, 20}))
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "hpa-app-canary"}, &autoscalingv2.HorizontalPodAutoscaler{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			Expect(pd.Status.Autoscaler).To(BeNil())
+		})
+
+		It("should hand the original bounds to the sibling HPA on completion", func() {
+			Expect(reconciler.trackAutoscaler(ctx, pd)).To(Succeed())
+			Expect(reconciler.splitAutoscaler(ctx, pd, 100)).To(Succeed())
+
+			Expect(reconciler.restoreAutoscaler(ctx, pd, true)).To(Succeed())
+			minReplicas, maxReplicas := bounds("hpa-app-canary")
+			Expect([]int32{minReplicas, maxReplicas}).To(Equal([]int32{4, 20}))
+			minReplicas, maxReplicas = bounds("hpa-app")
+			Expect([]int32{minReplicas, maxReplicas}).To(Equal([]int32{4, 20}))
+		})
+	})
+})
@@ -0,0 +1,400 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+// Istio kinds are handled as unstructured objects, so the operator does not depend on the Istio API
+var (
+	virtualServiceGVK  = schema.GroupVersionKind{Group: "networking.istio.io", Version: "v1beta1", Kind: "VirtualService"}
+	destinationRuleGVK = schema.GroupVersionKind{Group: "networking.istio.io", Version: "v1beta1", Kind: "DestinationRule"}
+)
+
+// istioRouter shifts traffic by weighting the destinations of named routes in a VirtualService.
+// The destinations are the per-version Services, or subsets of a DestinationRule
+type istioRouter struct {
+	*ProgressiveDeploymentReconciler
+}
+
+// Name returns "istio"
+func (r *istioRouter) Name() string {
+	return "istio"
+}
+
+// subsetNames returns the names of the stable and canary subsets, defaulted when unset
+func subsetNames(rule *appsv1alpha1.IstioDestinationRule) (stable, canary string) {
+	stable, canary = rule.StableSubsetName, rule.CanarySubsetName
+	if stable == "" {
+		stable = "stable"
+	}
+	if canary == "" {
+		canary = "canary"
+	}
+	return stable, canary
+}
+
+// istioDestinations returns the stable and canary route destinations
+func istioDestinations(pd *appsv1alpha1.ProgressiveDeployment) (stable, canary map[string]any) {
+	if rule := pd.Spec.TrafficRouting.Istio.DestinationRule; rule != nil {
+		stableSubset, canarySubset := subsetNames(rule)
+		return map[string]any{"host": pd.Spec.Service, "subset": stableSubset},
+			map[string]any{"host": pd.Spec.Service, "subset": canarySubset}
+	}
+	return map[string]any{"host": stableServiceName(pd)}, map[string]any{"host": canaryServiceName(pd)}
+}
+
+// setRouteWeights sets the destinations of the named HTTP routes to stable and canary,
+// weighted by weight, and mirrors requests to canary when mirror is set. The port of a
+// route's first destination is kept and routes that are not named are left untouched
+func setRouteWeights(virtualService *unstructured.Unstructured, routes []string, stable, canary map[string]any, weight int, mirror *appsv1alpha1.MirrorRoute) error {
+	httpRoutes, _, err := unstructured.NestedSlice(virtualService.Object, "spec", "http")
+	if err != nil {
+		return fmt.Errorf("invalid http routes in virtualservice %s: %w", virtualService.GetName(), err)
+	}
+
+	weight = min(max(weight, 0), 100)
+	found := make(map[string]bool, len(routes))
+	for i, item := range httpRoutes {
+		route, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		name, _ := route["name"].(string)
+		if !slices.Contains(routes, name) {
+			continue
+		}
+		found[name] = true
+
+		destinations := []any{
+			map[string]any{"destination": runtime.DeepCopyJSONValue(stable), "weight": int64(100 - weight)},
+			map[string]any{"destination": runtime.DeepCopyJSONValue(canary), "weight": int64(weight)},
+		}
+		var port any
+		if existing, ok := route["route"].([]any); ok && len(existing) > 0 {
+			if value, found, _ := unstructured.NestedFieldCopy(existing[0].(map[string]any), "destination", "port"); found {
+				port = value
+				for _, destination := range destinations {
+					destination.(map[string]any)["destination"].(map[string]any)["port"] = runtime.DeepCopyJSONValue(port)
+				}
+			}
+		}
+		route["route"] = destinations
+
+		delete(route, "mirror")
+		delete(route, "mirrorPercentage")
+		if mirror != nil {
+			target := runtime.DeepCopyJSONValue(canary).(map[string]any)
+			if port != nil {
+				target["port"] = runtime.DeepCopyJSONValue(port)
+			}
+			route["mirror"] = target
+			// A whole percentage reads back as an integer, store it as one to compare equal
+			route["mirrorPercentage"] = map[string]any{"value": int64(mirror.Percentage)}
+		}
+		httpRoutes[i] = route
+	}
+
+	for _, name := range routes {
+		if !found[name] {
+			return fmt.Errorf("route %q not found in virtualservice %s", name, virtualService.GetName())
+		}
+	}
+	return unstructured.SetNestedSlice(virtualService.Object, httpRoutes, "spec", "http")
+}
+
+// headerRouteName returns the name of the HTTP route a setHeaderRoute step adds to the VirtualService
+func headerRouteName(pd *appsv1alpha1.ProgressiveDeployment) string {
+	return fmt.Sprintf("%s-header-route", pd.Name)
+}
+
+// headerRouteMatch returns the Istio match of a header route. Istio matches lowercase header names,
+// and cookies through the Cookie header
+func headerRouteMatch(route *appsv1alpha1.HeaderRoute) map[string]any {
+	if route.Cookie != nil {
+		pattern := fmt.Sprintf(`^(.*?;\s*)?(%s=%s)(;.*)?$`, regexp.QuoteMeta(route.Cookie.Name), regexp.QuoteMeta(route.Cookie.Value))
+		return map[string]any{"headers": map[string]any{"cookie": map[string]any{"regex": pattern}}}
+	}
+	return map[string]any{"headers": map[string]any{
+		strings.ToLower(route.Header.Name): map[string]any{"exact": route.Header.Value},
+	}}
+}
+
+// setHeaderRouteRule adds or updates the HTTP route called name, sending the requests matching
+// match to canary. It is placed right before the first of the named routes, so it takes
+// precedence over them but not over the routes the user placed before them
+func setHeaderRouteRule(virtualService *unstructured.Unstructured, name string, routes []string, match map[string]any, canary map[string]any) error {
+	httpRoutes, _, err := unstructured.NestedSlice(virtualService.Object, "spec", "http")
+	if err != nil {
+		return fmt.Errorf("invalid http routes in virtualservice %s: %w", virtualService.GetName(), err)
+	}
+	httpRoutes = slices.DeleteFunc(httpRoutes, func(item any) bool {
+		route, ok := item.(map[string]any)
+		return ok && route["name"] == name
+	})
+
+	index := slices.IndexFunc(httpRoutes, func(item any) bool {
+		route, ok := item.(map[string]any)
+		return ok && slices.Contains(routes, fmt.Sprint(route["name"]))
+	})
+	if index < 0 {
+		return fmt.Errorf("none of the routes %v found in virtualservice %s", routes, virtualService.GetName())
+	}
+
+	destination := runtime.DeepCopyJSONValue(canary).(map[string]any)
+	if existing, ok := httpRoutes[index].(map[string]any)["route"].([]any); ok && len(existing) > 0 {
+		if port, found, _ := unstructured.NestedFieldCopy(existing[0].(map[string]any), "destination", "port"); found {
+			destination["port"] = port
+		}
+	}
+	headerRoute := map[string]any{
+		"name":  name,
+		"match": []any{runtime.DeepCopyJSONValue(match)},
+		"route": []any{map[string]any{"destination": destination, "weight": int64(100)}},
+	}
+	httpRoutes = slices.Insert(httpRoutes, index, any(headerRoute))
+	return unstructured.SetNestedSlice(virtualService.Object, httpRoutes, "spec", "http")
+}
+
+// removeHeaderRouteRule removes the HTTP route called name, if present
+func removeHeaderRouteRule(virtualService *unstructured.Unstructured, name string) error {
+	httpRoutes, found, err := unstructured.NestedSlice(virtualService.Object, "spec", "http")
+	if err != nil || !found {
+		return err
+	}
+	httpRoutes = slices.DeleteFunc(httpRoutes, func(item any) bool {
+		route, ok := item.(map[string]any)
+		return ok && route["name"] == name
+	})
+	return unstructured.SetNestedSlice(virtualService.Object, httpRoutes, "spec", "http")
+}
+
+// setSubsets points the named subsets of a DestinationRule at the given pod labels,
+// adding them when missing. Other subsets are left untouched
+func setSubsets(destinationRule *unstructured.Unstructured, subsets map[string]map[string]string) error {
+	items, _, err := unstructured.NestedSlice(destinationRule.Object, "spec", "subsets")
+	if err != nil {
+		return fmt.Errorf("invalid subsets in destinationrule %s: %w", destinationRule.GetName(), err)
+	}
+
+	toLabels := func(labels map[string]string) map[string]any {
+		result := make(map[string]any, len(labels))
+		for key, value := range labels {
+			result[key] = value
+		}
+		return result
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(subsets)) {
+		index := slices.IndexFunc(items, func(item any) bool {
+			subset, ok := item.(map[string]any)
+			return ok && subset["name"] == name
+		})
+		if index < 0 {
+			items = append(items, map[string]any{"name": name, "labels": toLabels(subsets[name])})
+			continue
+		}
+		items[index].(map[string]any)["labels"] = toLabels(subsets[name])
+	}
+	return unstructured.SetNestedSlice(destinationRule.Object, items, "spec", "subsets")
+}
+
+// getUnstructured fetches an object of the given kind from the ProgressiveDeployment namespace
+func (r *istioRouter) getUnstructured(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, gvk schema.GroupVersionKind, name string) (*unstructured.Unstructured, error) {
+	object := &unstructured.Unstructured{}
+	object.SetGroupVersionKind(gvk)
+	if err := r.Get(ctx, client.ObjectKey{Namespace: pd.Namespace, Name: name}, object); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+// updateIfChanged patches the fields mutate changed in object. A merge patch replaces whole
+// lists such as spec.http, so it is guarded by the resource version: when routes or subsets
+// changed in the meantime, mutate is applied again to the latest version instead
+func (r *istioRouter) updateIfChanged(ctx context.Context, object *unstructured.Unstructured, mutate func(*unstructured.Unstructured) error) (bool, error) {
+	changed := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		updated := object.DeepCopy()
+		if err := mutate(updated); err != nil {
+			return err
+		}
+		changed = !equality.Semantic.DeepEqual(object.Object, updated.Object)
+		if !changed {
+			return nil
+		}
+		err := r.Patch(ctx, updated, client.MergeFromWithOptions(object, client.MergeFromWithOptimisticLock{}))
+		if !errors.IsConflict(err) {
+			return err
+		}
+
+		latest := &unstructured.Unstructured{}
+		latest.SetGroupVersionKind(object.GroupVersionKind())
+		if getErr := r.apiReader().Get(ctx, client.ObjectKeyFromObject(object), latest); getErr != nil {
+			return getErr
+		}
+		object = latest
+		return err
+	})
+	return changed, err
+}
+
+// syncSubsets pins the stable and canary subsets to the current revision of each version
+func (r *istioRouter) syncSubsets(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) error {
+	rule := pd.Spec.TrafficRouting.Istio.DestinationRule
+	if rule == nil {
+		return nil
+	}
+	log := logf.FromContext(ctx)
+
+	stable, err := r.getTargetWorkload(ctx, pd)
+	if err != nil {
+		return err
+	}
+	canary, err := r.getWorkload(ctx, pd, pd.Status.CanaryDeployment)
+	if err != nil {
+		return err
+	}
+	stableKey, stableRevision, err := stable.RevisionLabel(ctx, r)
+	if err != nil {
+		return err
+	}
+	canaryKey, canaryRevision, err := canary.RevisionLabel(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	destinationRule, err := r.getUnstructured(ctx, pd, destinationRuleGVK, rule.Name)
+	if err != nil {
+		log.Error(err, "Failed to get destination rule", "name", rule.Name)
+		return err
+	}
+	stableSubset, canarySubset := subsetNames(rule)
+	changed, err := r.updateIfChanged(ctx, destinationRule, func(object *unstructured.Unstructured) error {
+		return setSubsets(object, map[string]map[string]string{
+			stableSubset: {stableKey: stableRevision},
+			canarySubset: {canaryKey: canaryRevision, trackLabelKey(pd): canary.Object().GetLabels()["deployment-type"]},
+		})
+	})
+	if err != nil {
+		log.Error(err, "Failed to update destination rule subsets", "name", rule.Name)
+		return err
+	}
+	if changed {
+		log.Info("Updated destination rule subsets", "name", rule.Name,
+			"stableRevision", stableRevision, "canaryRevision", canaryRevision)
+	}
+	return nil
+}
+
+// setWeight weights the named routes of the VirtualService and sets or removes their mirror.
+// The header route is removed when clearHeaderRoute is set
+func (r *istioRouter) setWeight(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, weight int, mirror *appsv1alpha1.MirrorRoute, clearHeaderRoute bool) error {
+	log := logf.FromContext(ctx)
+	istio := pd.Spec.TrafficRouting.Istio
+
+	virtualService, err := r.getUnstructured(ctx, pd, virtualServiceGVK, istio.VirtualService.Name)
+	if err != nil {
+		log.Error(err, "Failed to get virtual service", "name", istio.VirtualService.Name)
+		return err
+	}
+
+	stable, canary := istioDestinations(pd)
+	changed, err := r.updateIfChanged(ctx, virtualService, func(object *unstructured.Unstructured) error {
+		if clearHeaderRoute {
+			if err := removeHeaderRouteRule(object, headerRouteName(pd)); err != nil {
+				return err
+			}
+		}
+		return setRouteWeights(object, istio.VirtualService.Routes, stable, canary, weight, mirror)
+	})
+	if err != nil {
+		log.Error(err, "Failed to update virtual service routes", "name", istio.VirtualService.Name)
+		return err
+	}
+	if changed {
+		log.Info("Updated virtual service routes", "name", istio.VirtualService.Name,
+			"routes", istio.VirtualService.Routes, "weight", weight, "mirrored", mirror != nil)
+	}
+	return nil
+}
+
+// SetWeight pins the subsets, if any, then weights the routes. The routes keep
+// mirroring only during a setMirrorRoute step
+func (r *istioRouter) SetWeight(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, weight int) error {
+	if err := r.syncSubsets(ctx, pd); err != nil {
+		return err
+	}
+	return r.setWeight(ctx, pd, weight, activeMirrorRoute(pd), false)
+}
+
+// SetMirrorRoute mirrors a percentage of the requests of the named routes to the canary
+func (r *istioRouter) SetMirrorRoute(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, route *appsv1alpha1.MirrorRoute) error {
+	return r.setWeight(ctx, pd, pd.Status.CanaryPercentage, route, false)
+}
+
+// SetHeaderRoute adds an HTTP route sending the matching requests to the canary, ahead of the named routes
+func (r *istioRouter) SetHeaderRoute(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, route *appsv1alpha1.HeaderRoute) error {
+	log := logf.FromContext(ctx)
+	istio := pd.Spec.TrafficRouting.Istio
+
+	virtualService, err := r.getUnstructured(ctx, pd, virtualServiceGVK, istio.VirtualService.Name)
+	if err != nil {
+		log.Error(err, "Failed to get virtual service", "name", istio.VirtualService.Name)
+		return err
+	}
+
+	_, canary := istioDestinations(pd)
+	changed, err := r.updateIfChanged(ctx, virtualService, func(object *unstructured.Unstructured) error {
+		return setHeaderRouteRule(object, headerRouteName(pd), istio.VirtualService.Routes, headerRouteMatch(route), canary)
+	})
+	if err != nil {
+		log.Error(err, "Failed to set header route", "name", istio.VirtualService.Name)
+		return err
+	}
+	if changed {
+		log.Info("Set header route", "name", istio.VirtualService.Name, "route", headerRouteName(pd))
+	}
+	return nil
+}
+
+// Promote sends every request of the named routes to the new version, which now serves them for good
+func (r *istioRouter) Promote(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) error {
+	return r.setWeight(ctx, pd, 100, nil, true)
+}
+
+// Abort sends every request of the named routes back to stable
+func (r *istioRouter) Abort(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) error {
+	return r.setWeight(ctx, pd, 0, nil, true)
+}
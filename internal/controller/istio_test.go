@@ -0,0 +1,232 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+var _ = Describe("Istio router", func() {
+	ctx := context.Background()
+	var pd *appsv1alpha1.ProgressiveDeployment
+	var virtualService *unstructured.Unstructured
+
+	// httpRoutes returns the http routes of the stored VirtualService
+	httpRoutes := func() []any {
+		stored := &unstructured.Unstructured{}
+		stored.SetGroupVersionKind(virtualServiceGVK)
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(virtualService), stored)).To(Succeed())
+		routes, _, err := unstructured.NestedSlice(stored.Object, "spec", "http")
+		Expect(err).NotTo(HaveOccurred())
+		return routes
+	}
+
+	BeforeEach(func() {
+		virtualService = &unstructured.Unstructured{Object: map[string]any{
+			"spec": map[string]any{
+				"hosts": []any{"web.example.com"},
+				"http": []any{
+					map[string]any{
+						"name":  "internal",
+						"match": []any{map[string]any{"headers": map[string]any{"x-team": map[string]any{"exact": "qa"}}}},
+						"route": []any{map[string]any{"destination": map[string]any{"host": "web-qa"}}},
+					},
+					map[string]any{
+						"name":  "primary",
+						"match": []any{map[string]any{"uri": map[string]any{"prefix": "/"}}},
+						"route": []any{map[string]any{"destination": map[string]any{"host": "web", "port": map[string]any{"number": int64(80)}}}},
+					},
+				},
+			},
+		}}
+		virtualService.SetGroupVersionKind(virtualServiceGVK)
+		virtualService.SetName("web")
+		virtualService.SetNamespace("default")
+		Expect(k8sClient.Create(ctx, virtualService)).To(Succeed())
+
+		pd = &appsv1alpha1.ProgressiveDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "istio-test", Namespace: "default"},
+			Spec: appsv1alpha1.ProgressiveDeploymentSpec{
+				TargetDeployment: "web",
+				Service:          "web",
+				CanarySteps:      []int{10, 100},
+				TrafficRouting: &appsv1alpha1.TrafficRouting{
+					Istio: &appsv1alpha1.IstioTrafficRouting{
+						VirtualService: appsv1alpha1.IstioVirtualService{Name: "web", Routes: []string{"primary"}},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, pd)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(k8sClient.Delete(ctx, virtualService)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, pd)).To(Succeed())
+	})
+
+	It("should weight only the named routes between the stable and canary Services", func() {
+		reconciler := &ProgressiveDeploymentReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		router, err := reconciler.routerFor(pd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(router.Name()).To(Equal("istio"))
+
+		internal := httpRoutes()[0]
+		Expect(router.SetWeight(ctx, pd, 10)).To(Succeed())
+
+		routes := httpRoutes()
+		Expect(routes[0]).To(Equal(internal))
+		primary := routes[1].(map[string]any)
+		Expect(primary["match"]).To(HaveLen(1))
+		Expect(primary["route"]).To(Equal([]any{
+			map[string]any{"destination": map[string]any{"host": "web-stable", "port": map[string]any{"number": int64(80)}}, "weight": int64(90)},
+			map[string]any{"destination": map[string]any{"host": "web-canary", "port": map[string]any{"number": int64(80)}}, "weight": int64(10)},
+		}))
+
+		Expect(router.Abort(ctx, pd)).To(Succeed())
+		weights, _, err := unstructured.NestedSlice(httpRoutes()[1].(map[string]any), "route")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(weights[0].(map[string]any)["weight"]).To(Equal(int64(100)))
+		Expect(weights[1].(map[string]any)["weight"]).To(Equal(int64(0)))
+	})
+
+	It("should keep a route added while the weights are patched", func() {
+		racing := &racingWriter{Client: k8sClient}
+		racing.write = func() {
+			stored := &unstructured.Unstructured{}
+			stored.SetGroupVersionKind(virtualServiceGVK)
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(virtualService), stored)).To(Succeed())
+			routes, _, err := unstructured.NestedSlice(stored.Object, "spec", "http")
+			Expect(err).NotTo(HaveOccurred())
+			routes = append([]any{map[string]any{
+				"name":  "debug",
+				"match": []any{map[string]any{"headers": map[string]any{"x-debug": map[string]any{"exact": "1"}}}},
+				"route": []any{map[string]any{"destination": map[string]any{"host": "web-debug"}}},
+			}}, routes...)
+			Expect(unstructured.SetNestedSlice(stored.Object, routes, "spec", "http")).To(Succeed())
+			Expect(k8sClient.Update(ctx, stored)).To(Succeed())
+		}
+		reconciler := &ProgressiveDeploymentReconciler{Client: racing, APIReader: k8sClient, Scheme: k8sClient.Scheme()}
+		router := &istioRouter{reconciler}
+
+		Expect(router.SetWeight(ctx, pd, 10)).To(Succeed())
+		Expect(racing.patches).To(Equal(2))
+
+		routes := httpRoutes()
+		Expect(routes).To(HaveLen(3))
+		Expect(routes[0].(map[string]any)["name"]).To(Equal("debug"))
+		weights, _, err := unstructured.NestedSlice(routes[2].(map[string]any), "route")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(weights[1].(map[string]any)["weight"]).To(Equal(int64(10)))
+	})
+
+	It("should add a header route ahead of the named routes and remove it on rollback", func() {
+		reconciler := &ProgressiveDeploymentReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		router := &istioRouter{reconciler}
+		route := &appsv1alpha1.HeaderRoute{Header: &appsv1alpha1.RequestMatch{Name: "X-Canary", Value: "true"}}
+
+		Expect(router.SetWeight(ctx, pd, 0)).To(Succeed())
+		Expect(router.SetHeaderRoute(ctx, pd, route)).To(Succeed())
+		Expect(router.SetHeaderRoute(ctx, pd, route)).To(Succeed())
+
+		routes := httpRoutes()
+		Expect(routes).To(HaveLen(3))
+		Expect(routes[0].(map[string]any)["name"]).To(Equal("internal"))
+		Expect(routes[1]).To(Equal(map[string]any{
+			"name":  "istio-test-header-route",
+			"match": []any{map[string]any{"headers": map[string]any{"x-canary": map[string]any{"exact": "true"}}}},
+			"route": []any{map[string]any{
+				"destination": map[string]any{"host": "web-canary", "port": map[string]any{"number": int64(80)}},
+				"weight":      int64(100),
+			}},
+		}))
+		Expect(routes[2].(map[string]any)["name"]).To(Equal("primary"))
+
+		Expect(router.Abort(ctx, pd)).To(Succeed())
+		Expect(httpRoutes()).To(HaveLen(2))
+	})
+
+	It("should mirror requests during a setMirrorRoute step only", func() {
+		reconciler := &ProgressiveDeploymentReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		router := &istioRouter{reconciler}
+		pd.Spec.CanarySteps = nil
+		pd.Spec.Steps = []appsv1alpha1.CanaryStep{
+			{SetMirrorRoute: &appsv1alpha1.MirrorRoute{Percentage: 50}},
+			{SetWeight: ptr.To(100)},
+		}
+
+		Expect(router.SetWeight(ctx, pd, 0)).To(Succeed())
+		Expect(router.SetMirrorRoute(ctx, pd, activeMirrorRoute(pd))).To(Succeed())
+		primary := httpRoutes()[1].(map[string]any)
+		Expect(primary["mirror"]).To(Equal(map[string]any{"host": "web-canary", "port": map[string]any{"number": int64(80)}}))
+		Expect(primary["mirrorPercentage"]).To(Equal(map[string]any{"value": int64(50)}))
+		Expect(primary["route"].([]any)[1].(map[string]any)["weight"]).To(Equal(int64(0)))
+
+		// The next step stops mirroring
+		pd.Status.CurrentStep = 1
+		Expect(router.SetWeight(ctx, pd, 100)).To(Succeed())
+		primary = httpRoutes()[1].(map[string]any)
+		Expect(primary).NotTo(HaveKey("mirror"))
+		Expect(primary).NotTo(HaveKey("mirrorPercentage"))
+	})
+
+	It("should match cookies through the Cookie header", func() {
+		match := headerRouteMatch(&appsv1alpha1.HeaderRoute{Cookie: &appsv1alpha1.RequestMatch{Name: "canary", Value: "always"}})
+		Expect(match).To(Equal(map[string]any{"headers": map[string]any{
+			"cookie": map[string]any{"regex": `^(.*?;\s*)?(canary=always)(;.*)?$`},
+		}}))
+	})
+
+	It("should fail when a named route does not exist", func() {
+		err := setRouteWeights(virtualService.DeepCopy(), []string{"missing"}, nil, nil, 10, nil)
+		Expect(err).To(MatchError(ContainSubstring(`route "missing" not found`)))
+	})
+
+	It("should pin the named subsets and keep the others", func() {
+		destinationRule := &unstructured.Unstructured{Object: map[string]any{
+			"spec": map[string]any{
+				"host": "web",
+				"subsets": []any{
+					map[string]any{"name": "legacy", "labels": map[string]any{"version": "v0"}},
+					map[string]any{"name": "stable", "labels": map[string]any{"version": "v1"}},
+				},
+			},
+		}}
+
+		Expect(setSubsets(destinationRule, map[string]map[string]string{
+			"stable": {podTemplateHashLabel: "aaa"},
+			"canary": {podTemplateHashLabel: "bbb", defaultTrackLabelKey: "canary"},
+		})).To(Succeed())
+
+		subsets, _, err := unstructured.NestedSlice(destinationRule.Object, "spec", "subsets")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(subsets).To(Equal([]any{
+			map[string]any{"name": "legacy", "labels": map[string]any{"version": "v0"}},
+			map[string]any{"name": "stable", "labels": map[string]any{podTemplateHashLabel: "aaa"}},
+			map[string]any{"name": "canary", "labels": map[string]any{podTemplateHashLabel: "bbb", defaultTrackLabelKey: "canary"}},
+		}))
+	})
+})
@@ -0,0 +1,205 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+// defaultTrackLabelKey is used when spec.trackLabelKey is not set
+const defaultTrackLabelKey = "bgswitch.io/track"
+
+// uncopiedAnnotations are owned by other controllers and never copied to a clone
+var uncopiedAnnotations = []string{
+	revisionAnnotation,
+	corev1.LastAppliedConfigAnnotation,
+}
+
+// trackLabelKey returns the pod label separating the new version's pods from the stable ones
+func trackLabelKey(pd *appsv1alpha1.ProgressiveDeployment) string {
+	if pd.Spec.TrackLabelKey == "" {
+		return defaultTrackLabelKey
+	}
+	return pd.Spec.TrackLabelKey
+}
+
+// cloneMetadata returns the metadata of a clone of the target: its labels and annotations,
+// plus the labels tying the clone to the ProgressiveDeployment
+func cloneMetadata(pd *appsv1alpha1.ProgressiveDeployment, target client.Object, name, role string) metav1.ObjectMeta {
+	cloneLabels := maps.Clone(target.GetLabels())
+	if cloneLabels == nil {
+		cloneLabels = make(map[string]string)
+	}
+	cloneLabels["progressive-deployment"] = pd.Name
+	cloneLabels["deployment-type"] = role
+
+	annotations := maps.Clone(target.GetAnnotations())
+	maps.DeleteFunc(annotations, func(key, _ string) bool {
+		return slices.Contains(uncopiedAnnotations, key)
+	})
+
+	return metav1.ObjectMeta{
+		Name:        name,
+		Namespace:   pd.Namespace,
+		Labels:      cloneLabels,
+		Annotations: annotations,
+	}
+}
+
+// trackPods labels the clone's pods with key=role and adds the same requirement to its selector,
+// so the clone never selects a stable pod. It fails when the stable pods already carry that label,
+// unless the clone is the stable workload managed in GitOps mode
+func trackPods(key, role string, stableTemplate *corev1.PodTemplateSpec, template *corev1.PodTemplateSpec, selector *metav1.LabelSelector) error {
+	if stableTemplate.Labels[key] == role && role != stableRole {
+		return fmt.Errorf("the target's pods are already labelled %s=%s, choose another spec.trackLabelKey", key, role)
+	}
+
+	if template.Labels == nil {
+		template.Labels = make(map[string]string)
+	}
+	template.Labels[key] = role
+
+	if selector.MatchLabels == nil {
+		selector.MatchLabels = make(map[string]string)
+	}
+	selector.MatchLabels[key] = role
+
+	// A requirement of the target's selector on the key would contradict the new label
+	selector.MatchExpressions = slices.DeleteFunc(selector.MatchExpressions, func(requirement metav1.LabelSelectorRequirement) bool {
+		return requirement.Key == key
+	})
+	return nil
+}
+
+// allowedValues returns the values a selector allows for key. The second result is
+// false when the selector allows any value, and an empty set means the key must be absent
+func allowedValues(selector *metav1.LabelSelector, key string) (map[string]bool, bool) {
+	var allowed map[string]bool
+	restrict := func(values ...string) {
+		next := make(map[string]bool, len(values))
+		for _, value := range values {
+			if allowed == nil || allowed[value] {
+				next[value] = true
+			}
+		}
+		allowed = next
+	}
+
+	if value, ok := selector.MatchLabels[key]; ok {
+		restrict(value)
+	}
+	for _, requirement := range selector.MatchExpressions {
+		if requirement.Key != key {
+			continue
+		}
+		switch requirement.Operator {
+		case metav1.LabelSelectorOpIn:
+			restrict(requirement.Values...)
+		case metav1.LabelSelectorOpDoesNotExist:
+			restrict()
+		}
+	}
+	return allowed, allowed != nil
+}
+
+// excludedValues returns the values a selector rejects for key, and whether it rejects the key being absent
+func excludedValues(selector *metav1.LabelSelector, key string) (map[string]bool, bool) {
+	excluded := make(map[string]bool)
+	absentExcluded := false
+	if _, ok := selector.MatchLabels[key]; ok {
+		absentExcluded = true
+	}
+	for _, requirement := range selector.MatchExpressions {
+		if requirement.Key != key {
+			continue
+		}
+		switch requirement.Operator {
+		case metav1.LabelSelectorOpNotIn:
+			for _, value := range requirement.Values {
+				excluded[value] = true
+			}
+		case metav1.LabelSelectorOpIn, metav1.LabelSelectorOpExists:
+			absentExcluded = true
+		}
+	}
+	return excluded, absentExcluded
+}
+
+// selectorsDisjoint reports whether no set of labels can match both selectors.
+// It looks for a key on which the requirements of the two selectors contradict each other
+func selectorsDisjoint(a, b *metav1.LabelSelector) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	if _, err := metav1.LabelSelectorAsSelector(a); err != nil {
+		return false
+	}
+	if _, err := metav1.LabelSelectorAsSelector(b); err != nil {
+		return false
+	}
+
+	keys := labels.Set{}
+	for _, selector := range []*metav1.LabelSelector{a, b} {
+		for key := range selector.MatchLabels {
+			keys[key] = ""
+		}
+		for _, requirement := range selector.MatchExpressions {
+			keys[requirement.Key] = ""
+		}
+	}
+
+	for key := range keys {
+		for _, pair := range [][2]*metav1.LabelSelector{{a, b}, {b, a}} {
+			allowed, restricted := allowedValues(pair[0], key)
+			if !restricted {
+				continue
+			}
+			otherAllowed, otherRestricted := allowedValues(pair[1], key)
+			excluded, absentExcluded := excludedValues(pair[1], key)
+
+			// One side requires the key to be absent, the other requires it to be present
+			if len(allowed) == 0 {
+				if absentExcluded {
+					return true
+				}
+				continue
+			}
+
+			// Every value one side allows is rejected by the other
+			contradicts := true
+			for value := range allowed {
+				if excluded[value] || (otherRestricted && !otherAllowed[value]) {
+					continue
+				}
+				contradicts = false
+			}
+			if contradicts {
+				return true
+			}
+		}
+	}
+	return false
+}
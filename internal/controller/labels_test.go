@@ -0,0 +1,107 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+var _ = Describe("Label management", func() {
+	pd := &appsv1alpha1.ProgressiveDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+	}
+
+	It("should copy the target's labels and annotations except controller-owned ones", func() {
+		target := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{"team": "payments"},
+			Annotations: map[string]string{
+				"owner":                            "payments@example.com",
+				revisionAnnotation:                 "7",
+				corev1.LastAppliedConfigAnnotation: "{}",
+			},
+		}}
+
+		metadata := cloneMetadata(pd, target, "web-canary", "canary")
+		Expect(metadata.Labels).To(Equal(map[string]string{
+			"team":                   "payments",
+			"progressive-deployment": "web",
+			"deployment-type":        "canary",
+		}))
+		Expect(metadata.Annotations).To(Equal(map[string]string{"owner": "payments@example.com"}))
+	})
+
+	It("should keep the pod labels and add the track label to the pods and selector", func() {
+		stable := &corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"name": "web"}}}
+		template := stable.DeepCopy()
+		selector := &metav1.LabelSelector{
+			MatchLabels: map[string]string{"name": "web"},
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: defaultTrackLabelKey, Operator: metav1.LabelSelectorOpIn, Values: []string{"stable"}},
+			},
+		}
+
+		Expect(trackPods(defaultTrackLabelKey, "canary", stable, template, selector)).To(Succeed())
+		Expect(template.Labels).To(Equal(map[string]string{"name": "web", defaultTrackLabelKey: "canary"}))
+		Expect(selector.MatchLabels).To(Equal(map[string]string{"name": "web", defaultTrackLabelKey: "canary"}))
+		Expect(selector.MatchExpressions).To(BeEmpty())
+
+		// The canary selector never matches the stable pods
+		canarySelector, err := metav1.LabelSelectorAsSelector(selector)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(canarySelector.Matches(labels.Set(stable.Labels))).To(BeFalse())
+	})
+
+	It("should refuse a track label the stable pods already carry", func() {
+		stable := &corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"version": "canary"}}}
+		err := trackPods("version", "canary", stable, stable.DeepCopy(), &metav1.LabelSelector{})
+		Expect(err).To(MatchError(ContainSubstring("already labelled version=canary")))
+	})
+
+	DescribeTable("proving selectors disjoint",
+		func(a, b *metav1.LabelSelector, disjoint bool) {
+			Expect(selectorsDisjoint(a, b)).To(Equal(disjoint))
+			Expect(selectorsDisjoint(b, a)).To(Equal(disjoint))
+		},
+		Entry("different values for the same key",
+			&metav1.LabelSelector{MatchLabels: map[string]string{"app": "web", "track": "stable"}},
+			&metav1.LabelSelector{MatchLabels: map[string]string{"app": "web", "track": "canary"}},
+			true),
+		Entry("canary is a subset of stable",
+			&metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			&metav1.LabelSelector{MatchLabels: map[string]string{"app": "web", "track": "canary"}},
+			false),
+		Entry("NotIn excludes the other value",
+			&metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "track", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"canary"}}}},
+			&metav1.LabelSelector{MatchLabels: map[string]string{"track": "canary"}},
+			true),
+		Entry("DoesNotExist against a required key",
+			&metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "track", Operator: metav1.LabelSelectorOpDoesNotExist}}},
+			&metav1.LabelSelector{MatchLabels: map[string]string{"track": "canary"}},
+			true),
+		Entry("overlapping In sets",
+			&metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "track", Operator: metav1.LabelSelectorOpIn, Values: []string{"stable", "canary"}}}},
+			&metav1.LabelSelector{MatchLabels: map[string]string{"track": "canary"}},
+			false),
+	)
+})
@@ -2,30 +2,90 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+	"github.com/ghanatava/bg-switch/internal/analysis"
 	promapi "github.com/prometheus/client_golang/api"
 	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
-	"github.com/prometheus/common/model"
+	corev1 "k8s.io/api/core/v1"
+	"net/http"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"time"
 )
 
+// defaultPrometheusURL is queried when spec.metrics.prometheusUrl is not set.
+// Assumes Prometheus is in same namespace as operator
+const defaultPrometheusURL = "http://prometheus:9090"
+
+// DefaultAnalysisTimeout bounds each metric query of an analysis when AnalysisTimeout is not set
+const DefaultAnalysisTimeout = DefaultMetricsQueryTimeout
+
+// analysisConcurrency is the number of metric queries an analysis runs at once
+const analysisConcurrency = 4
+
+// reasonAnalysisInconclusive is the reason of the event reporting an analysis that could not decide
+const reasonAnalysisInconclusive = "AnalysisInconclusive"
+
+// defaultInconclusiveLimit is how many analyses of a step in a row may be inconclusive
+// when spec.metrics.inconclusiveLimit is not set
+const defaultInconclusiveLimit = 5
+
+// inconclusiveLimit returns how many analyses of a step in a row may be inconclusive before the rollout is rolled back
+func inconclusiveLimit(pd *appsv1alpha1.ProgressiveDeployment) int32 {
+	if pd.Spec.Metrics.InconclusiveLimit == nil {
+		return defaultInconclusiveLimit
+	}
+	return *pd.Spec.Metrics.InconclusiveLimit
+}
+
+// Keys of the Prometheus credentials Secret
+const (
+	metricsTokenKey    = "token"
+	metricsUsernameKey = "username"
+	metricsPasswordKey = "password"
+)
+
+// MetricsEndpoint is the Prometheus a rollout queries and the credentials it queries it with
+type MetricsEndpoint struct {
+	// URL of the Prometheus API
+	URL string
+	// AuthRef is the namespace/name of the Secret the credentials come from, empty without credentials
+	AuthRef string
+	// BearerToken is sent in the Authorization header when set
+	BearerToken string
+	// Username and Password are sent with basic auth when Username is set
+	Username string
+	Password string
+}
+
 // MetricsClient wraps the Prometheus API client
 type MetricsClient struct {
 	api promv1.API
+
+	// timeout bounds each query, zero leaves it to the caller's context
+	timeout time.Duration
+	// slots bounds the queries in flight, nil does not limit them
+	slots chan struct{}
 }
 
 // NewMetricsClient creates and returns a new MetricsClient
 func NewMetricsClient(prometheusURL string) (*MetricsClient, error) {
-	if prometheusURL == "" {
-		// Layer 3: Fallback to common default
-		// Assumes Prometheus is in same namespace as operator
-		prometheusURL = "http://prometheus:9090"
+	return newMetricsClient(MetricsEndpoint{URL: prometheusURL}, promapi.DefaultRoundTripper)
+}
+
+// newMetricsClient creates a MetricsClient sending its requests, with the endpoint's
+// credentials, through transport
+func newMetricsClient(endpoint MetricsEndpoint, transport http.RoundTripper) (*MetricsClient, error) {
+	address := endpoint.URL
+	if address == "" {
+		address = defaultPrometheusURL
 	}
 
 	client, err := promapi.NewClient(promapi.Config{
-		Address: prometheusURL,
+		Address:      address,
+		RoundTripper: &authRoundTripper{endpoint: endpoint, next: transport},
 	})
 	if err != nil {
 		return nil, fmt.Errorf("error creating prometheus client: %w", err)
@@ -36,14 +96,53 @@ func NewMetricsClient(prometheusURL string) (*MetricsClient, error) {
 	}, nil
 }
 
+// authRoundTripper adds the credentials of an endpoint to every request
+type authRoundTripper struct {
+	endpoint MetricsEndpoint
+	next     http.RoundTripper
+}
+
+// RoundTrip sends the request with a bearer token or basic auth, if the endpoint has either
+func (t *authRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch {
+	case t.endpoint.BearerToken != "":
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", "Bearer "+t.endpoint.BearerToken)
+	case t.endpoint.Username != "":
+		req = req.Clone(req.Context())
+		req.SetBasicAuth(t.endpoint.Username, t.endpoint.Password)
+	}
+	return t.next.RoundTrip(req)
+}
+
 // QueryMetric executes a PromQL query and returns a single float64 value
 func (m *MetricsClient) QueryMetric(ctx context.Context, query string) (float64, error) {
+	return m.Query(ctx, query, time.Now())
+}
+
+// Query executes a PromQL query evaluated at a point in time and returns a single float64 value.
+// The query waits for a free slot and gives up after the client's timeout
+func (m *MetricsClient) Query(ctx context.Context, query string, at time.Time) (float64, error) {
 	log := log.FromContext(ctx)
 
+	if m.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.timeout)
+		defer cancel()
+	}
+	if m.slots != nil {
+		select {
+		case m.slots <- struct{}{}:
+			defer func() { <-m.slots }()
+		case <-ctx.Done():
+			return 0, fmt.Errorf("error querying prometheus: waiting for a free query slot: %w", ctx.Err())
+		}
+	}
+
 	log.Info("Querying Prometheus", "query", query)
 
-	// Execute the query at current time
-	result, warnings, err := m.api.Query(ctx, query, time.Now())
+	// Execute the query at the requested time
+	result, warnings, err := m.api.Query(ctx, query, at)
 	if err != nil {
 		return 0, fmt.Errorf("error querying prometheus: %w", err)
 	}
@@ -53,105 +152,137 @@ func (m *MetricsClient) QueryMetric(ctx context.Context, query string) (float64,
 		log.Info("Prometheus query warnings", "warnings", warnings)
 	}
 
-	// Parse the result based on its type
-	switch v := result.(type) {
-	case model.Vector:
-		// Vector: array of time series with current values
-		if len(v) == 0 {
-			return 0, fmt.Errorf("no data returned from query")
-		}
-		// Return the first sample's value
-		return float64(v[0].Value), nil
-
-	case *model.Scalar:
-		// Scalar: single numeric value
-		return float64(v.Value), nil
-
-	default:
-		// Unexpected result type
-		return 0, fmt.Errorf("unexpected result type: %T", result)
-	}
+	return analysis.SampleValue(result)
 }
 
-// AnalyzeHealth checks all configured metrics against their thresholds
-// Returns: (isHealthy bool, actualMetrics map, error)
-func (m *MetricsClient) AnalyzeHealth(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (bool, map[string]float64, error) {
-	log := log.FromContext(ctx)
+// MetricsClientFactory returns the querier for a Prometheus endpoint
+type MetricsClientFactory func(endpoint MetricsEndpoint) (analysis.Querier, error)
 
-	// Map to store actual metric values
-	metrics := make(map[string]float64)
+// defaultMetricsClients is the MetricsClientCache of NewPrometheusQuerier
+var defaultMetricsClients = NewMetricsClientCache(DefaultMetricsQueryTimeout, DefaultMaxConcurrentQueries)
 
-	// Start optimistic - assume healthy until proven otherwise
-	healthy := true
+// NewPrometheusQuerier is the MetricsClientFactory sharing clients through a MetricsClientCache
+// with the default query timeout and limit
+func NewPrometheusQuerier(endpoint MetricsEndpoint) (analysis.Querier, error) {
+	return defaultMetricsClients.Get(endpoint)
+}
 
-	// Check error rate if configured
-	if pd.Spec.Metrics.ErrorRate.Query != "" {
-		log.Info("Checking error rate metric")
+// metricsClient returns the querier for spec.metrics from the injected factory
+func (r *ProgressiveDeploymentReconciler) metricsClient(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (analysis.Querier, error) {
+	endpoint, err := r.metricsEndpoint(ctx, pd)
+	if err != nil {
+		return nil, err
+	}
+	if r.NewMetricsClient == nil {
+		return NewPrometheusQuerier(endpoint)
+	}
+	return r.NewMetricsClient(endpoint)
+}
 
-		errorRate, err := m.QueryMetric(ctx, pd.Spec.Metrics.ErrorRate.Query)
-		if err != nil {
-			log.Error(err, "Failed to query error rate metric")
-			return false, nil, fmt.Errorf("error rate query failed: %w", err)
-		}
+// metricsEndpoint resolves spec.metrics into the endpoint to query, reading its credentials
+func (r *ProgressiveDeploymentReconciler) metricsEndpoint(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (MetricsEndpoint, error) {
+	endpoint := MetricsEndpoint{URL: pd.Spec.Metrics.PrometheusURL}
+	if endpoint.URL == "" {
+		endpoint.URL = defaultPrometheusURL
+	}
 
-		// Store the actual value
-		metrics["errorRate"] = errorRate
-
-		log.Info("Error rate metric result",
-			"value", errorRate,
-			"threshold", pd.Spec.Metrics.ErrorRate.Threshold)
-
-		// Check threshold
-		if errorRate > pd.Spec.Metrics.ErrorRate.Threshold {
-			log.Info("❌ Error rate EXCEEDED threshold - UNHEALTHY",
-				"value", errorRate,
-				"threshold", pd.Spec.Metrics.ErrorRate.Threshold)
-			healthy = false
-		} else {
-			log.Info("✅ Error rate within threshold - OK")
-		}
+	ref := pd.Spec.Metrics.AuthSecretRef
+	if ref == nil {
+		return endpoint, nil
+	}
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: pd.Namespace, Name: ref.Name}, secret); err != nil {
+		return endpoint, fmt.Errorf("failed to get metrics auth secret %q: %w", ref.Name, err)
 	}
 
-	// Check latency if configured
-	if pd.Spec.Metrics.Latency.Query != "" {
-		log.Info("Checking latency metric")
+	return endpoint.WithCredentials(secret)
+}
 
-		latency, err := m.QueryMetric(ctx, pd.Spec.Metrics.Latency.Query)
-		if err != nil {
-			log.Error(err, "Failed to query latency metric")
-			return false, nil, fmt.Errorf("latency query failed: %w", err)
-		}
+// WithCredentials returns the endpoint with the credentials of a Secret laid out like
+// spec.metrics.authSecretRef: a token key, or username and password keys
+func (e MetricsEndpoint) WithCredentials(secret *corev1.Secret) (MetricsEndpoint, error) {
+	e.AuthRef = fmt.Sprintf("%s/%s", secret.Namespace, secret.Name)
+	e.BearerToken = string(secret.Data[metricsTokenKey])
+	e.Username = string(secret.Data[metricsUsernameKey])
+	e.Password = string(secret.Data[metricsPasswordKey])
+	if e.BearerToken == "" && e.Username == "" {
+		return e, fmt.Errorf("metrics auth secret %q has neither a %q nor a %q key", secret.Name, metricsTokenKey, metricsUsernameKey)
+	}
+	return e, nil
+}
 
-		// Store the actual value
-		metrics["latency"] = latency
-
-		log.Info("Latency metric result",
-			"value", latency,
-			"threshold", pd.Spec.Metrics.Latency.Threshold)
-
-		// Check threshold
-		if latency > pd.Spec.Metrics.Latency.Threshold {
-			log.Info("❌ Latency EXCEEDED threshold - UNHEALTHY",
-				"value", latency,
-				"threshold", pd.Spec.Metrics.Latency.Threshold)
-			healthy = false
-		} else {
-			log.Info("✅ Latency within threshold - OK")
-		}
+// analysisOptions bounds the queries of an analysis
+func (r *ProgressiveDeploymentReconciler) analysisOptions() analysis.Options {
+	timeout := r.AnalysisTimeout
+	if timeout <= 0 {
+		timeout = DefaultAnalysisTimeout
 	}
+	return analysis.Options{Concurrency: analysisConcurrency, Timeout: timeout}
+}
+
+// analyzeHealth checks all configured metrics against their thresholds.
+// The queries run concurrently, a query that does not complete in time makes the result inconclusive
+func analyzeHealth(ctx context.Context, querier analysis.Querier, pd *appsv1alpha1.ProgressiveDeployment, opts analysis.Options) analysis.Result {
+	log := log.FromContext(ctx)
 
 	// If no metrics configured at all, assume healthy
-	if len(metrics) == 0 {
+	checks := analysis.ChecksFor(pd.Spec.Metrics)
+	if len(checks) == 0 {
 		log.Info("⚠️  No metrics configured - assuming healthy")
-		return true, metrics, nil
+		return analysis.Result{Healthy: true}
+	}
+
+	// Measure every configured metric and compare the measurements with their thresholds
+	result := analysis.Analyze(ctx, querier, checks, time.Now(), opts)
+	for _, check := range result.Checks {
+		switch {
+		case check.Inconclusive:
+			log.Info("⏳ Metric query did not complete in time",
+				"metric", check.Name, "timeout", opts.Timeout)
+		case check.Err != nil:
+			log.Error(check.Err, "Failed to query metric", "metric", check.Name)
+		case check.Passed:
+			log.Info("✅ Metric within threshold - OK",
+				"metric", check.Name, "value", check.Value, "threshold", check.Threshold)
+		default:
+			log.Info("❌ Metric EXCEEDED threshold - UNHEALTHY",
+				"metric", check.Name, "value", check.Value, "threshold", check.Threshold)
+		}
 	}
 
 	// Return final health status
-	if healthy {
+	metrics := measuredMetrics(result)
+	switch {
+	case result.Healthy:
 		log.Info("✅ Overall health: HEALTHY", "metrics", metrics)
-	} else {
+	case result.Inconclusive:
+		log.Info("⏳ Overall health: INCONCLUSIVE", "metrics", metrics)
+	default:
 		log.Info("❌ Overall health: UNHEALTHY", "metrics", metrics)
 	}
 
-	return healthy, metrics, nil
+	return result
+}
+
+// measuredMetrics returns the measured values of an analysis keyed by metric name
+func measuredMetrics(result analysis.Result) map[string]float64 {
+	metrics := make(map[string]float64, len(result.Checks))
+	for _, check := range result.Checks {
+		if check.Measured {
+			metrics[check.Name] = check.Value
+		}
+	}
+	return metrics
+}
+
+// queryFailures joins the errors of the queries that failed, in check order.
+// Queries that did not complete in time are not failures, they make the analysis inconclusive
+func queryFailures(result analysis.Result) error {
+	var errs []error
+	for _, check := range result.Checks {
+		if check.Err != nil && !check.Inconclusive {
+			errs = append(errs, check.Err)
+		}
+	}
+	return errors.Join(errs...)
 }
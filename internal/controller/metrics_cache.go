@@ -0,0 +1,154 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	promapi "github.com/prometheus/client_golang/api"
+
+	"github.com/ghanatava/bg-switch/internal/analysis"
+)
+
+// Defaults of the MetricsClientCache
+const (
+	DefaultMetricsQueryTimeout  = 30 * time.Second
+	DefaultMaxConcurrentQueries = 10
+)
+
+// metricsClientIdleTTL is how long a cached client may go unused before it is dropped,
+// which releases the clients of endpoints and Secrets no rollout refers to anymore
+const metricsClientIdleTTL = 10 * time.Minute
+
+// MetricsClientCache shares one MetricsClient, and its pooled connections, between the
+// rollouts that query the same endpoint with the same credentials. Every query waits for
+// one of the endpoint's slots and is bounded by the query timeout. Get is a MetricsClientFactory
+type MetricsClientCache struct {
+	queryTimeout         time.Duration
+	maxConcurrentQueries int
+
+	mu sync.Mutex
+	// clients are keyed by endpoint URL and AuthRef
+	clients map[string]*cachedMetricsClient
+	// slots are keyed by endpoint URL, so all the clients of an endpoint share its limit
+	slots map[string]chan struct{}
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// cachedMetricsClient is a client and the configuration it was built with
+type cachedMetricsClient struct {
+	// credentials is a hash of the credentials the client sends
+	credentials string
+	client      *MetricsClient
+	transport   *http.Transport
+	lastUsed    time.Time
+}
+
+// NewMetricsClientCache creates an empty MetricsClientCache. A zero queryTimeout uses
+// DefaultMetricsQueryTimeout, a zero maxConcurrentQueries disables the limit
+func NewMetricsClientCache(queryTimeout time.Duration, maxConcurrentQueries int) *MetricsClientCache {
+	if queryTimeout <= 0 {
+		queryTimeout = DefaultMetricsQueryTimeout
+	}
+	return &MetricsClientCache{
+		queryTimeout:         queryTimeout,
+		maxConcurrentQueries: maxConcurrentQueries,
+		clients:              map[string]*cachedMetricsClient{},
+		slots:                map[string]chan struct{}{},
+		now:                  time.Now,
+	}
+}
+
+// Get returns the client of an endpoint, building it on first use and again when the
+// credentials of the endpoint changed
+func (c *MetricsClientCache) Get(endpoint MetricsEndpoint) (analysis.Querier, error) {
+	key := endpoint.URL + "|" + endpoint.AuthRef
+	credentials := computeHash([]string{endpoint.BearerToken, endpoint.Username, endpoint.Password})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.evictIdle(now)
+
+	if cached, ok := c.clients[key]; ok {
+		if cached.credentials == credentials {
+			cached.lastUsed = now
+			return cached.client, nil
+		}
+		// The Secret was rotated, stop reusing connections opened with the old credentials
+		cached.transport.CloseIdleConnections()
+		delete(c.clients, key)
+	}
+
+	transport := c.newTransport()
+	client, err := newMetricsClient(endpoint, transport)
+	if err != nil {
+		return nil, err
+	}
+	client.timeout = c.queryTimeout
+	client.slots = c.slotsFor(endpoint.URL)
+
+	c.clients[key] = &cachedMetricsClient{
+		credentials: credentials,
+		client:      client,
+		transport:   transport,
+		lastUsed:    now,
+	}
+	return client, nil
+}
+
+// newTransport returns a transport of its own for a client, keeping no more connections
+// to the endpoint than queries may be in flight
+func (c *MetricsClientCache) newTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if base, ok := promapi.DefaultRoundTripper.(*http.Transport); ok {
+		transport = base.Clone()
+	}
+	if c.maxConcurrentQueries > 0 {
+		transport.MaxConnsPerHost = c.maxConcurrentQueries
+		transport.MaxIdleConnsPerHost = c.maxConcurrentQueries
+	}
+	return transport
+}
+
+// slotsFor returns the query slots of an endpoint, nil without a limit
+func (c *MetricsClientCache) slotsFor(url string) chan struct{} {
+	if c.maxConcurrentQueries <= 0 {
+		return nil
+	}
+	slots, ok := c.slots[url]
+	if !ok {
+		slots = make(chan struct{}, c.maxConcurrentQueries)
+		c.slots[url] = slots
+	}
+	return slots
+}
+
+// evictIdle drops the clients unused for metricsClientIdleTTL and closes their connections.
+// Slots are kept, a query may still hold one
+func (c *MetricsClientCache) evictIdle(now time.Time) {
+	for key, cached := range c.clients {
+		if now.Sub(cached.lastUsed) > metricsClientIdleTTL {
+			cached.transport.CloseIdleConnections()
+			delete(c.clients, key)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+// prometheusResponse is an instant query result with a single sample
+const prometheusResponse = `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1700000000,"0.01"]}]}}`
+
+var _ = Describe("Metrics client cache", func() {
+	ctx := context.Background()
+
+	It("should reuse the client of an endpoint with the same credentials", func() {
+		cache := NewMetricsClientCache(time.Second, 2)
+		endpoint := MetricsEndpoint{URL: "http://prometheus:9090", AuthRef: "default/prom", BearerToken: "a"}
+
+		first, err := cache.Get(endpoint)
+		Expect(err).NotTo(HaveOccurred())
+		second, err := cache.Get(endpoint)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second).To(BeIdenticalTo(first))
+
+		other, err := cache.Get(MetricsEndpoint{URL: "http://prometheus:9090"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(other).NotTo(BeIdenticalTo(first))
+	})
+
+	It("should share clients between analyses without a factory", func() {
+		endpoint := MetricsEndpoint{URL: "http://shared-prometheus:9090"}
+		first, err := NewPrometheusQuerier(endpoint)
+		Expect(err).NotTo(HaveOccurred())
+		second, err := NewPrometheusQuerier(endpoint)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second).To(BeIdenticalTo(first))
+	})
+
+	It("should use the default query timeout for a zero timeout", func() {
+		querier, err := NewMetricsClientCache(0, 0).Get(MetricsEndpoint{URL: "http://prometheus:9090"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(querier.(*MetricsClient).timeout).To(Equal(DefaultMetricsQueryTimeout))
+		Expect(querier.(*MetricsClient).slots).To(BeNil())
+	})
+
+	It("should build a new client when the credentials change", func() {
+		cache := NewMetricsClientCache(time.Second, 2)
+		endpoint := MetricsEndpoint{URL: "http://prometheus:9090", AuthRef: "default/prom", BearerToken: "a"}
+		first, err := cache.Get(endpoint)
+		Expect(err).NotTo(HaveOccurred())
+
+		endpoint.BearerToken = "b"
+		rotated, err := cache.Get(endpoint)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rotated).NotTo(BeIdenticalTo(first))
+		Expect(cache.clients).To(HaveLen(1))
+	})
+
+	It("should drop clients that went unused", func() {
+		cache := NewMetricsClientCache(time.Second, 2)
+		now := time.Now()
+		cache.now = func() time.Time { return now }
+		_, err := cache.Get(MetricsEndpoint{URL: "http://old-prometheus:9090"})
+		Expect(err).NotTo(HaveOccurred())
+
+		now = now.Add(metricsClientIdleTTL + time.Minute)
+		_, err = cache.Get(MetricsEndpoint{URL: "http://prometheus:9090"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cache.clients).To(HaveLen(1))
+		Expect(cache.clients).To(HaveKey("http://prometheus:9090|"))
+	})
+
+	It("should send the credentials of the endpoint", func() {
+		var authorization atomic.Value
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization.Store(r.Header.Get("Authorization"))
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprint(w, prometheusResponse)
+		}))
+		defer server.Close()
+
+		cache := NewMetricsClientCache(time.Second, 2)
+		querier, err := cache.Get(MetricsEndpoint{URL: server.URL, AuthRef: "default/prom", BearerToken: "secret-token"})
+		Expect(err).NotTo(HaveOccurred())
+		value, err := querier.Query(ctx, "errors", time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).To(Equal(0.01))
+		Expect(authorization.Load()).To(Equal("Bearer secret-token"))
+	})
+
+	It("should give up on a query after the query timeout", func() {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}))
+		defer server.Close()
+		defer close(release)
+
+		querier, err := NewMetricsClientCache(100*time.Millisecond, 2).Get(MetricsEndpoint{URL: server.URL})
+		Expect(err).NotTo(HaveOccurred())
+		start := time.Now()
+		_, err = querier.Query(ctx, "errors", time.Now())
+		Expect(err).To(MatchError(ContainSubstring("deadline exceeded")))
+		Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+	})
+
+	It("should limit the queries in flight against an endpoint", func() {
+		var inFlight, maxInFlight int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			current := atomic.AddInt32(&inFlight, 1)
+			defer atomic.AddInt32(&inFlight, -1)
+			for {
+				seen := atomic.LoadInt32(&maxInFlight)
+				if current <= seen || atomic.CompareAndSwapInt32(&maxInFlight, seen, current) {
+					break
+				}
+			}
+			time.Sleep(50 * time.Millisecond)
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprint(w, prometheusResponse)
+		}))
+		defer server.Close()
+
+		cache := NewMetricsClientCache(5*time.Second, 2)
+		var wg sync.WaitGroup
+		for i := range 6 {
+			// Rollouts with different credentials still share the endpoint's limit
+			querier, err := cache.Get(MetricsEndpoint{URL: server.URL, AuthRef: fmt.Sprintf("default/prom-%d", i%2), BearerToken: "t"})
+			Expect(err).NotTo(HaveOccurred())
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				_, err := querier.Query(ctx, "errors", time.Now())
+				Expect(err).NotTo(HaveOccurred())
+			}()
+		}
+		wg.Wait()
+		Expect(atomic.LoadInt32(&maxInFlight)).To(BeNumerically("<=", 2))
+	})
+
+	It("should read the credentials of spec.metrics.authSecretRef", func() {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "prometheus-auth", Namespace: "default"},
+			Data:       map[string][]byte{"username": []byte("bgswitch"), "password": []byte("hunter2")},
+		}
+		Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+		defer func() { Expect(k8sClient.Delete(ctx, secret)).To(Succeed()) }()
+
+		pd := &appsv1alpha1.ProgressiveDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: appsv1alpha1.ProgressiveDeploymentSpec{Metrics: appsv1alpha1.MetricsConfig{
+				AuthSecretRef: &corev1.LocalObjectReference{Name: "prometheus-auth"},
+			}},
+		}
+		reconciler := &ProgressiveDeploymentReconciler{Client: k8sClient}
+		endpoint, err := reconciler.metricsEndpoint(ctx, pd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(endpoint).To(Equal(MetricsEndpoint{
+			URL:      defaultPrometheusURL,
+			AuthRef:  "default/prometheus-auth",
+			Username: "bgswitch",
+			Password: "hunter2",
+		}))
+
+		pd.Spec.Metrics.AuthSecretRef.Name = "missing"
+		_, err = reconciler.metricsEndpoint(ctx, pd)
+		Expect(err).To(HaveOccurred())
+	})
+})
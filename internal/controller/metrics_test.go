@@ -0,0 +1,197 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+	"github.com/ghanatava/bg-switch/internal/analysis"
+	"github.com/ghanatava/bg-switch/internal/analysis/fake"
+)
+
+var _ = Describe("Metric analysis", func() {
+	ctx := context.Background()
+	var pd *appsv1alpha1.ProgressiveDeployment
+
+	BeforeEach(func() {
+		pd = &appsv1alpha1.ProgressiveDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "analysis-test", Namespace: "default"},
+			Spec: appsv1alpha1.ProgressiveDeploymentSpec{
+				TargetDeployment: "analysis-app",
+				CanarySteps:      []int{10, 50, 100},
+				StepDuration:     metav1.Duration{Duration: time.Minute},
+				Metrics: appsv1alpha1.MetricsConfig{
+					PrometheusURL: "http://prometheus.monitoring:9090",
+					ErrorRate:     appsv1alpha1.MetricThreshold{Query: "errors", Threshold: 0.05},
+					Latency:       appsv1alpha1.MetricThreshold{Query: "latency", Threshold: 0.5},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, pd)).To(Succeed())
+
+		analysisStarted := metav1.NewTime(time.Now().Add(-2 * time.Minute))
+		pd.Status.Phase = "Analyzing"
+		pd.Status.CurrentStep = 1
+		pd.Status.CanaryPercentage = 50
+		pd.Status.LastAnalysisTime = &analysisStarted
+		Expect(k8sClient.Status().Update(ctx, pd)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(k8sClient.Delete(ctx, pd)).To(Succeed())
+	})
+
+	DescribeTable("deciding at the end of an analysis window",
+		func(querier *fake.Querier, factoryErr error, phase, health, reason string) {
+			var requestedURL string
+			reconciler := &ProgressiveDeploymentReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				NewMetricsClient: func(endpoint MetricsEndpoint) (analysis.Querier, error) {
+					requestedURL = endpoint.URL
+					return querier, factoryErr
+				},
+				AnalysisTimeout: 50 * time.Millisecond,
+			}
+			_, _ = reconciler.handleAnalyzing(ctx, pd)
+
+			Expect(requestedURL).To(Equal("http://prometheus.monitoring:9090"))
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pd), pd)).To(Succeed())
+			Expect(pd.Status.Phase).To(Equal(phase))
+			Expect(pd.Status.HealthStatus).To(Equal(health))
+			Expect(pd.Status.RollbackReason).To(ContainSubstring(reason))
+		},
+		Entry("promotes when every metric is within its threshold",
+			&fake.Querier{Values: map[string]float64{"errors": 0.01, "latency": 0.3}}, nil,
+			"Promoting", "Healthy", ""),
+		Entry("rolls back when a metric exceeds its threshold",
+			&fake.Querier{Values: map[string]float64{"errors": 0.09, "latency": 0.3}}, nil,
+			"RollingBack", "Unhealthy", "metrics exceeded thresholds at step 1"),
+		Entry("rolls back when a query fails",
+			&fake.Querier{Errors: map[string]error{"latency": errors.New("connection refused")}, Values: map[string]float64{"errors": 0.01}}, nil,
+			"RollingBack", "Unhealthy", "latency query failed: connection refused"),
+		Entry("analyzes again when a query does not complete in time",
+			&fake.Querier{Values: map[string]float64{"errors": 0.01, "latency": 0.3}, Delays: map[string]time.Duration{"latency": time.Minute}}, nil,
+			"Analyzing", "Unknown", ""),
+		Entry("rolls back when a metric exceeds its threshold while another query is slow",
+			&fake.Querier{Values: map[string]float64{"errors": 0.09, "latency": 0.3}, Delays: map[string]time.Duration{"latency": time.Minute}}, nil,
+			"RollingBack", "Unhealthy", "metrics exceeded thresholds at step 1"),
+		Entry("fails when there is no metrics client",
+			nil, errors.New("invalid prometheus URL"),
+			"Failed", "Unknown", ""),
+	)
+
+	It("should keep the analysis window open while the analysis is inconclusive", func() {
+		querier := &fake.Querier{
+			Values: map[string]float64{"errors": 0.01, "latency": 0.3},
+			Delays: map[string]time.Duration{"errors": time.Minute},
+		}
+		reconciler := &ProgressiveDeploymentReconciler{
+			Client: k8sClient,
+			Scheme: k8sClient.Scheme(),
+			NewMetricsClient: func(MetricsEndpoint) (analysis.Querier, error) {
+				return querier, nil
+			},
+			AnalysisTimeout: 50 * time.Millisecond,
+		}
+		result, err := reconciler.handleAnalyzing(ctx, pd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(readinessPollInterval))
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pd), pd)).To(Succeed())
+		Expect(pd.Status.LastAnalysisTime).NotTo(BeNil())
+		Expect(pd.Status.StepResults).To(BeEmpty())
+		Expect(pd.Status.Metrics).To(Equal(map[string]float64{analysis.Latency: 0.3}))
+	})
+
+	It("should roll back after too many inconclusive analyses in a row", func() {
+		pd.Spec.Metrics.InconclusiveLimit = ptr.To[int32](2)
+		Expect(k8sClient.Update(ctx, pd)).To(Succeed())
+		pd.Status.InconclusiveAnalyses = 1
+		Expect(k8sClient.Status().Update(ctx, pd)).To(Succeed())
+
+		querier := &fake.Querier{
+			Values: map[string]float64{"errors": 0.01, "latency": 0.3},
+			Delays: map[string]time.Duration{"errors": time.Minute},
+		}
+		reconciler := &ProgressiveDeploymentReconciler{
+			Client: k8sClient,
+			Scheme: k8sClient.Scheme(),
+			NewMetricsClient: func(MetricsEndpoint) (analysis.Querier, error) {
+				return querier, nil
+			},
+			AnalysisTimeout: 50 * time.Millisecond,
+		}
+		_, err := reconciler.handleAnalyzing(ctx, pd)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pd), pd)).To(Succeed())
+		Expect(pd.Status.Phase).To(Equal("RollingBack"))
+		Expect(pd.Status.InconclusiveAnalyses).To(Equal(int32(2)))
+		Expect(pd.Status.RollbackReason).To(ContainSubstring("metric analysis inconclusive 2 times in a row at step 1"))
+		Expect(pd.Status.StepResults).To(HaveLen(1))
+		Expect(pd.Status.StepResults[0].Healthy).To(BeFalse())
+	})
+
+	It("should count inconclusive analyses again after a conclusive one", func() {
+		pd.Status.InconclusiveAnalyses = 3
+		Expect(k8sClient.Status().Update(ctx, pd)).To(Succeed())
+
+		querier := &fake.Querier{Values: map[string]float64{"errors": 0.01, "latency": 0.3}}
+		reconciler := &ProgressiveDeploymentReconciler{
+			Client: k8sClient,
+			Scheme: k8sClient.Scheme(),
+			NewMetricsClient: func(MetricsEndpoint) (analysis.Querier, error) {
+				return querier, nil
+			},
+		}
+		_, err := reconciler.handleAnalyzing(ctx, pd)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pd), pd)).To(Succeed())
+		Expect(pd.Status.Phase).To(Equal("Promoting"))
+		Expect(pd.Status.InconclusiveAnalyses).To(BeZero())
+	})
+
+	It("should record the measured values of the step", func() {
+		querier := &fake.Querier{Values: map[string]float64{"errors": 0.01, "latency": 0.3}}
+		reconciler := &ProgressiveDeploymentReconciler{
+			Client: k8sClient,
+			Scheme: k8sClient.Scheme(),
+			NewMetricsClient: func(MetricsEndpoint) (analysis.Querier, error) {
+				return querier, nil
+			},
+		}
+		_, err := reconciler.handleAnalyzing(ctx, pd)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(querier.Queries()).To(ConsistOf("errors", "latency"))
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pd), pd)).To(Succeed())
+		Expect(pd.Status.Metrics).To(Equal(map[string]float64{analysis.ErrorRate: 0.01, analysis.Latency: 0.3}))
+		Expect(pd.Status.StepResults).To(HaveLen(1))
+		Expect(pd.Status.StepResults[0].Healthy).To(BeTrue())
+	})
+})
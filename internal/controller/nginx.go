@@ -0,0 +1,278 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+// Annotations read by ingress-nginx on a canary Ingress
+const (
+	nginxAnnotationPrefix            = "nginx.ingress.kubernetes.io/"
+	nginxCanaryAnnotation            = nginxAnnotationPrefix + "canary"
+	nginxCanaryWeightAnnotation      = nginxAnnotationPrefix + "canary-weight"
+	nginxCanaryByHeaderAnnotation    = nginxAnnotationPrefix + "canary-by-header"
+	nginxCanaryHeaderValueAnnotation = nginxAnnotationPrefix + "canary-by-header-value"
+	nginxCanaryByCookieAnnotation    = nginxAnnotationPrefix + "canary-by-cookie"
+)
+
+// nginxRouter shifts traffic with an ingress-nginx canary Ingress: a copy of the stable
+// Ingress routing to <service>-canary, weighted by the canary-weight annotation
+type nginxRouter struct {
+	*ProgressiveDeploymentReconciler
+}
+
+// Name returns "nginx"
+func (r *nginxRouter) Name() string {
+	return "nginx"
+}
+
+// canaryIngressName returns the name of the canary Ingress cloned from the stable Ingress
+func canaryIngressName(pd *appsv1alpha1.ProgressiveDeployment) string {
+	return fmt.Sprintf("%s-canary", pd.Spec.TrafficRouting.Nginx.StableIngress)
+}
+
+// isCanaryAnnotation reports whether an annotation configures ingress-nginx canary behaviour
+func isCanaryAnnotation(key string) bool {
+	return strings.HasPrefix(key, nginxCanaryAnnotation)
+}
+
+// canaryIngressSpec copies the rules of the stable Ingress that route to the primary Service,
+// pointing them at the canary Service. Other rules and TLS are left to the stable Ingress
+func canaryIngressSpec(stable *networkingv1.Ingress, service, canaryService string) (networkingv1.IngressSpec, error) {
+	rewrite := func(backend *networkingv1.IngressBackend) bool {
+		if backend == nil || backend.Service == nil || backend.Service.Name != service {
+			return false
+		}
+		backend.Service.Name = canaryService
+		return true
+	}
+
+	spec := networkingv1.IngressSpec{IngressClassName: stable.Spec.IngressClassName}
+	if backend := stable.Spec.DefaultBackend.DeepCopy(); rewrite(backend) {
+		spec.DefaultBackend = backend
+	}
+	for _, rule := range stable.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		var paths []networkingv1.HTTPIngressPath
+		for _, path := range rule.HTTP.Paths {
+			path = *path.DeepCopy()
+			if rewrite(&path.Backend) {
+				paths = append(paths, path)
+			}
+		}
+		if len(paths) > 0 {
+			spec.Rules = append(spec.Rules, networkingv1.IngressRule{
+				Host:             rule.Host,
+				IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{Paths: paths}},
+			})
+		}
+	}
+
+	if spec.DefaultBackend == nil && len(spec.Rules) == 0 {
+		return spec, fmt.Errorf("ingress %s has no backend routing to service %s", stable.Name, service)
+	}
+	return spec, nil
+}
+
+// canaryIngressAnnotations returns the ingress-nginx annotations of the stable Ingress,
+// with the canary ones set for weight and the configured header and cookie. A header
+// route replaces the configured header and cookie
+func canaryIngressAnnotations(stable *networkingv1.Ingress, nginx *appsv1alpha1.NginxTrafficRouting, weight int, route *appsv1alpha1.HeaderRoute) (map[string]string, error) {
+	annotations := make(map[string]string)
+	for key, value := range stable.Annotations {
+		if strings.HasPrefix(key, nginxAnnotationPrefix) && !isCanaryAnnotation(key) {
+			annotations[key] = value
+		}
+	}
+
+	annotations[nginxCanaryAnnotation] = "true"
+	annotations[nginxCanaryWeightAnnotation] = strconv.Itoa(min(max(weight, 0), 100))
+	header, headerValue, cookie := nginx.Header, nginx.HeaderValue, nginx.Cookie
+	if route != nil {
+		header, headerValue, cookie = "", "", ""
+		switch {
+		case route.Header != nil:
+			header, headerValue = route.Header.Name, route.Header.Value
+		case route.Cookie != nil:
+			// ingress-nginx only sends a request to the canary when its cookie is set to "always"
+			if route.Cookie.Value != "always" {
+				return nil, fmt.Errorf("ingress-nginx only matches cookie %s with the value %q", route.Cookie.Name, "always")
+			}
+			cookie = route.Cookie.Name
+		}
+	}
+
+	if header != "" {
+		annotations[nginxCanaryByHeaderAnnotation] = header
+		if headerValue != "" {
+			annotations[nginxCanaryHeaderValueAnnotation] = headerValue
+		}
+	}
+	if cookie != "" {
+		annotations[nginxCanaryByCookieAnnotation] = cookie
+	}
+	return annotations, nil
+}
+
+// SetWeight creates or updates the canary Ingress with the given weight
+func (r *nginxRouter) SetWeight(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, weight int) error {
+	return r.syncCanaryIngress(ctx, pd, weight, activeHeaderRoute(pd))
+}
+
+// SetHeaderRoute sets the canary-by-header or canary-by-cookie annotation of the canary Ingress
+func (r *nginxRouter) SetHeaderRoute(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, route *appsv1alpha1.HeaderRoute) error {
+	return r.syncCanaryIngress(ctx, pd, pd.Status.CanaryPercentage, route)
+}
+
+// syncCanaryIngress creates or updates the canary Ingress
+func (r *nginxRouter) syncCanaryIngress(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, weight int, route *appsv1alpha1.HeaderRoute) error {
+	log := logf.FromContext(ctx)
+	nginx := pd.Spec.TrafficRouting.Nginx
+
+	stable := &networkingv1.Ingress{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: pd.Namespace, Name: nginx.StableIngress}, stable); err != nil {
+		log.Error(err, "Failed to get stable ingress", "ingress", nginx.StableIngress)
+		return err
+	}
+	if stable.Annotations[nginxCanaryAnnotation] == "true" {
+		return fmt.Errorf("ingress %s is itself a canary Ingress", stable.Name)
+	}
+
+	spec, err := canaryIngressSpec(stable, pd.Spec.Service, canaryServiceName(pd))
+	if err != nil {
+		return err
+	}
+	annotations, err := canaryIngressAnnotations(stable, nginx, weight, route)
+	if err != nil {
+		return err
+	}
+	name := canaryIngressName(pd)
+
+	existing := &networkingv1.Ingress{}
+	err = r.Get(ctx, client.ObjectKey{Namespace: pd.Namespace, Name: name}, existing)
+	switch {
+	case errors.IsNotFound(err):
+		ingress := &networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   pd.Namespace,
+				Annotations: annotations,
+				Labels:      map[string]string{"progressive-deployment": pd.Name},
+			},
+			Spec: spec,
+		}
+		if err := ctrl.SetControllerReference(pd, ingress, r.Scheme); err != nil {
+			return err
+		}
+		if err := r.Create(ctx, ingress); err != nil {
+			log.Error(err, "Failed to create canary ingress")
+			return err
+		}
+		log.Info("Created canary ingress", "name", name, "weight", weight)
+		return nil
+	case err != nil:
+		return err
+	}
+
+	if !metav1.IsControlledBy(existing, pd) {
+		return fmt.Errorf("ingress %s already exists and is not managed by this ProgressiveDeployment", name)
+	}
+
+	// Only the canary annotations are owned here, other writers keep theirs. The patch is guarded
+	// by the resource version and applied again to the latest version on a conflict
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		updated := existing.DeepCopy()
+		if updated.Annotations == nil {
+			updated.Annotations = map[string]string{}
+		}
+		maps.DeleteFunc(updated.Annotations, func(key, _ string) bool {
+			_, desired := annotations[key]
+			return isCanaryAnnotation(key) && !desired
+		})
+		maps.Copy(updated.Annotations, annotations)
+		updated.Spec = spec
+		if equality.Semantic.DeepEqual(existing.Annotations, updated.Annotations) &&
+			equality.Semantic.DeepEqual(existing.Spec, updated.Spec) {
+			return nil
+		}
+		err := r.Patch(ctx, updated, client.MergeFromWithOptions(existing, client.MergeFromWithOptimisticLock{}))
+		if errors.IsConflict(err) {
+			latest := &networkingv1.Ingress{}
+			if getErr := r.apiReader().Get(ctx, client.ObjectKeyFromObject(existing), latest); getErr != nil {
+				return getErr
+			}
+			existing = latest
+			return err
+		}
+		if err != nil {
+			log.Error(err, "Failed to update canary ingress")
+			return err
+		}
+		log.Info("Updated canary ingress", "name", name, "weight", weight,
+			"previousWeight", existing.Annotations[nginxCanaryWeightAnnotation])
+		return nil
+	})
+}
+
+// Promote deletes the canary Ingress, the stable Ingress reaches the new version through the primary Service
+func (r *nginxRouter) Promote(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) error {
+	return r.deleteCanaryIngress(ctx, pd)
+}
+
+// Abort deletes the canary Ingress so every request goes through the stable Ingress again
+func (r *nginxRouter) Abort(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) error {
+	return r.deleteCanaryIngress(ctx, pd)
+}
+
+// deleteCanaryIngress deletes the canary Ingress if this ProgressiveDeployment created it
+func (r *nginxRouter) deleteCanaryIngress(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) error {
+	log := logf.FromContext(ctx)
+	name := canaryIngressName(pd)
+
+	ingress := &networkingv1.Ingress{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: pd.Namespace, Name: name}, ingress); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !metav1.IsControlledBy(ingress, pd) {
+		log.Info("Canary ingress is not managed by this ProgressiveDeployment, leaving it", "name", name)
+		return nil
+	}
+	if err := r.Delete(ctx, ingress); client.IgnoreNotFound(err) != nil {
+		log.Error(err, "Failed to delete canary ingress")
+		return err
+	}
+	log.Info("Deleted canary ingress", "name", name)
+	return nil
+}
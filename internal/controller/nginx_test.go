@@ -0,0 +1,304 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+var _ = Describe("Ingress-NGINX router", func() {
+	ctx := context.Background()
+	var pd *appsv1alpha1.ProgressiveDeployment
+	var stable *networkingv1.Ingress
+
+	backend := func(service string) networkingv1.IngressBackend {
+		return networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+			Name: service, Port: networkingv1.ServiceBackendPort{Number: 80},
+		}}
+	}
+
+	BeforeEach(func() {
+		stable = &networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "web",
+				Namespace: "default",
+				Annotations: map[string]string{
+					"nginx.ingress.kubernetes.io/rewrite-target": "/",
+					"cert-manager.io/cluster-issuer":             "letsencrypt",
+				},
+			},
+			Spec: networkingv1.IngressSpec{
+				IngressClassName: ptr.To("nginx"),
+				TLS:              []networkingv1.IngressTLS{{Hosts: []string{"web.example.com"}, SecretName: "web-tls"}},
+				Rules: []networkingv1.IngressRule{{
+					Host: "web.example.com",
+					IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{
+							{Path: "/", PathType: ptr.To(networkingv1.PathTypePrefix), Backend: backend("web")},
+							{Path: "/docs", PathType: ptr.To(networkingv1.PathTypePrefix), Backend: backend("docs")},
+						},
+					}},
+				}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, stable)).To(Succeed())
+
+		pd = &appsv1alpha1.ProgressiveDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "nginx-test", Namespace: "default"},
+			Spec: appsv1alpha1.ProgressiveDeploymentSpec{
+				TargetDeployment: "web",
+				Service:          "web",
+				CanarySteps:      []int{20, 100},
+				TrafficRouting: &appsv1alpha1.TrafficRouting{
+					Nginx: &appsv1alpha1.NginxTrafficRouting{StableIngress: "web", Header: "X-Canary", HeaderValue: "true"},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, pd)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		canary := &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: "web-canary", Namespace: "default"}}
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, canary))).To(Succeed())
+		Expect(k8sClient.Delete(ctx, stable)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, pd)).To(Succeed())
+	})
+
+	It("should clone the stable Ingress into a weighted canary Ingress and delete it on rollback", func() {
+		reconciler := &ProgressiveDeploymentReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		router, err := reconciler.routerFor(pd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(router.Name()).To(Equal("nginx"))
+
+		Expect(router.SetWeight(ctx, pd, 20)).To(Succeed())
+
+		canary := &networkingv1.Ingress{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "web-canary"}, canary)).To(Succeed())
+		Expect(metav1.IsControlledBy(canary, pd)).To(BeTrue())
+		Expect(canary.Annotations).To(Equal(map[string]string{
+			"nginx.ingress.kubernetes.io/rewrite-target":         "/",
+			nginxCanaryAnnotation:                                "true",
+			nginxCanaryWeightAnnotation:                          "20",
+			nginxCanaryByHeaderAnnotation:                        "X-Canary",
+			"nginx.ingress.kubernetes.io/canary-by-header-value": "true",
+		}))
+		Expect(canary.Spec.IngressClassName).To(Equal(ptr.To("nginx")))
+		Expect(canary.Spec.TLS).To(BeEmpty())
+		Expect(canary.Spec.Rules).To(HaveLen(1))
+		Expect(canary.Spec.Rules[0].Host).To(Equal("web.example.com"))
+		Expect(canary.Spec.Rules[0].HTTP.Paths).To(HaveLen(1))
+		Expect(canary.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name).To(Equal("web-canary"))
+
+		// The next step only changes the weight
+		Expect(router.SetWeight(ctx, pd, 50)).To(Succeed())
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(canary), canary)).To(Succeed())
+		Expect(canary.Annotations).To(HaveKeyWithValue(nginxCanaryWeightAnnotation, "50"))
+
+		Expect(router.Abort(ctx, pd)).To(Succeed())
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(canary), canary)
+		Expect(errors.IsNotFound(err)).To(BeTrue())
+
+		// The stable Ingress is never modified
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(stable), stable)).To(Succeed())
+		Expect(stable.Annotations).NotTo(HaveKey(nginxCanaryAnnotation))
+	})
+
+	It("should keep the annotations other writers add to the canary Ingress", func() {
+		reconciler := &ProgressiveDeploymentReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		router, err := reconciler.routerFor(pd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(router.SetWeight(ctx, pd, 20)).To(Succeed())
+
+		canary := &networkingv1.Ingress{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "web-canary"}, canary)).To(Succeed())
+		canary.Annotations["example.com/owner"] = "platform"
+		Expect(k8sClient.Update(ctx, canary)).To(Succeed())
+
+		// Dropping the header removes its canary annotations only
+		pd.Spec.TrafficRouting.Nginx.Header = ""
+		pd.Spec.TrafficRouting.Nginx.HeaderValue = ""
+		Expect(router.SetWeight(ctx, pd, 50)).To(Succeed())
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(canary), canary)).To(Succeed())
+		Expect(canary.Annotations).To(HaveKeyWithValue("example.com/owner", "platform"))
+		Expect(canary.Annotations).To(HaveKeyWithValue(nginxCanaryWeightAnnotation, "50"))
+		Expect(canary.Annotations).NotTo(HaveKey(nginxCanaryByHeaderAnnotation))
+		Expect(canary.Annotations).NotTo(HaveKey(nginxCanaryHeaderValueAnnotation))
+	})
+
+	It("should patch the canary Ingress again when it changed after it was read", func() {
+		reconciler := &ProgressiveDeploymentReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		router, err := reconciler.routerFor(pd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(router.SetWeight(ctx, pd, 20)).To(Succeed())
+
+		racing := &racingWriter{Client: k8sClient}
+		racing.write = func() {
+			canary := &networkingv1.Ingress{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "web-canary"}, canary)).To(Succeed())
+			canary.Annotations["example.com/owner"] = "platform"
+			Expect(k8sClient.Update(ctx, canary)).To(Succeed())
+		}
+		reconciler = &ProgressiveDeploymentReconciler{Client: racing, APIReader: k8sClient, Scheme: k8sClient.Scheme()}
+		router, err = reconciler.routerFor(pd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(router.SetWeight(ctx, pd, 50)).To(Succeed())
+		Expect(racing.patches).To(Equal(2))
+
+		canary := &networkingv1.Ingress{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "web-canary"}, canary)).To(Succeed())
+		Expect(canary.Annotations).To(HaveKeyWithValue("example.com/owner", "platform"))
+		Expect(canary.Annotations).To(HaveKeyWithValue(nginxCanaryWeightAnnotation, "50"))
+	})
+
+	It("should replace the configured header with the header route of a setHeaderRoute step", func() {
+		nginx := pd.Spec.TrafficRouting.Nginx
+		route := &appsv1alpha1.HeaderRoute{Header: &appsv1alpha1.RequestMatch{Name: "X-QA", Value: "1"}}
+		annotations, err := canaryIngressAnnotations(stable, nginx, 0, route)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(annotations).To(HaveKeyWithValue(nginxCanaryWeightAnnotation, "0"))
+		Expect(annotations).To(HaveKeyWithValue(nginxCanaryByHeaderAnnotation, "X-QA"))
+		Expect(annotations).To(HaveKeyWithValue(nginxCanaryHeaderValueAnnotation, "1"))
+
+		route = &appsv1alpha1.HeaderRoute{Cookie: &appsv1alpha1.RequestMatch{Name: "canary", Value: "yes"}}
+		_, err = canaryIngressAnnotations(stable, nginx, 0, route)
+		Expect(err).To(MatchError(ContainSubstring(`with the value "always"`)))
+	})
+
+	It("should refuse an Ingress that does not route to the primary Service", func() {
+		_, err := canaryIngressSpec(stable, "api", "api-canary")
+		Expect(err).To(MatchError(ContainSubstring("no backend routing to service api")))
+	})
+
+	It("should reject traffic routing without a Service or with the blueGreen strategy", func() {
+		invalid := pd.DeepCopy()
+		invalid.Spec.Service = ""
+		Expect(validateTrafficRouting(invalid)).To(MatchError(ContainSubstring("requires spec.service")))
+
+		invalid = pd.DeepCopy()
+		invalid.Spec.Strategy = appsv1alpha1.StrategyBlueGreen
+		Expect(validateTrafficRouting(invalid)).To(MatchError(ContainSubstring("only supported by the canary strategy")))
+	})
+
+	Context("When stable scales dynamically", func() {
+		var target *appsv1.Deployment
+
+		// markAvailable reports every desired replica of a Deployment as updated and available
+		markAvailable := func(name string) {
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: name}, deployment)).To(Succeed())
+			replicas := *deployment.Spec.Replicas
+			deployment.Status = appsv1.DeploymentStatus{
+				ObservedGeneration: deployment.Generation, Replicas: replicas, UpdatedReplicas: replicas, AvailableReplicas: replicas,
+			}
+			Expect(k8sClient.Status().Update(ctx, deployment)).To(Succeed())
+		}
+
+		replicasOf := func(name string) int32 {
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: name}, deployment)).To(Succeed())
+			return *deployment.Spec.Replicas
+		}
+
+		BeforeEach(func() {
+			labels := map[string]string{"app": "web"}
+			target = &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: ptr.To[int32](4),
+					Selector: &metav1.LabelSelector{MatchLabels: labels},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: labels},
+						Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "web", Image: "nginx:1.27"}}},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, target)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.Delete(ctx, target)).To(Succeed())
+			canary := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web-canary", Namespace: "default"}}
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, canary))).To(Succeed())
+		})
+
+		It("should shrink stable only after the router shifted the weight and restore it on rollback", func() {
+			reconciler := &ProgressiveDeploymentReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+			strategy := &canaryStrategy{reconciler}
+			pd.Spec.DynamicStableScale = true
+
+			workload, err := reconciler.getTargetWorkload(ctx, pd)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = strategy.Init(ctx, pd, workload)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pd.Status.StableReplicas).To(Equal(ptr.To[int32](4)))
+
+			// The canary has to be ready before stable gives up a replica
+			pd.Status.CanaryPercentage = 50
+			Expect(strategy.ApplyStep(ctx, pd)).To(BeFalse())
+			Expect(replicasOf("web-canary")).To(Equal(int32(2)))
+			Expect(replicasOf("web")).To(Equal(int32(4)))
+
+			markAvailable("web-canary")
+			Expect(strategy.ApplyStep(ctx, pd)).To(BeTrue())
+			Expect(replicasOf("web")).To(Equal(int32(2)))
+
+			Expect(strategy.Abort(ctx, pd)).To(Succeed())
+			Expect(replicasOf("web")).To(Equal(int32(4)))
+			Expect(replicasOf("web-canary")).To(Equal(int32(0)))
+			Expect(pd.Status.StableReplicas).To(BeNil())
+		})
+
+		It("should keep stable at full size by default", func() {
+			reconciler := &ProgressiveDeploymentReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+			strategy := &canaryStrategy{reconciler}
+
+			workload, err := reconciler.getTargetWorkload(ctx, pd)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = strategy.Init(ctx, pd, workload)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pd.Status.StableReplicas).To(BeNil())
+
+			pd.Status.CanaryPercentage = 50
+			Expect(strategy.ApplyStep(ctx, pd)).To(BeFalse())
+			markAvailable("web-canary")
+			Expect(strategy.ApplyStep(ctx, pd)).To(BeTrue())
+			Expect(replicasOf("web-canary")).To(Equal(int32(2)))
+			Expect(replicasOf("web")).To(Equal(int32(4)))
+		})
+	})
+
+	DescribeTable("sizing a routed canary",
+		func(stableReplicas int32, weight int, expected int32) {
+			Expect(routedCanaryReplicas(replicaSplit{}, stableReplicas, weight)).To(Equal(expected))
+		},
+		Entry("rounds up", int32(10), 15, int32(2)),
+		Entry("keeps one replica at zero weight", int32(10), 0, int32(1)),
+		Entry("matches stable at full weight", int32(10), 100, int32(10)),
+	)
+})
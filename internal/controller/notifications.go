@@ -0,0 +1,85 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+	"github.com/ghanatava/bg-switch/internal/notify"
+)
+
+// Keys of the notification Secret
+const (
+	notificationURLKey   = "url"
+	notificationTokenKey = "token"
+)
+
+// notifierFor builds the notifier of a spec.notifications entry from its Secret
+func (r *ProgressiveDeploymentReconciler) notifierFor(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, spec appsv1alpha1.NotificationSpec) (notify.Notifier, error) {
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: pd.Namespace, Name: spec.SecretRef.Name}, secret); err != nil {
+		return nil, fmt.Errorf("failed to get notification secret %q: %w", spec.SecretRef.Name, err)
+	}
+
+	return notify.New(notify.Config{
+		Type:     spec.Type,
+		URL:      string(secret.Data[notificationURLKey]),
+		Token:    string(secret.Data[notificationTokenKey]),
+		Template: spec.Template,
+	}, nil)
+}
+
+// notify queues a rollout event for every notification subscribed to the trigger.
+// Notifications are best effort: failures are logged and never fail the reconcile
+func (r *ProgressiveDeploymentReconciler) notify(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, trigger appsv1alpha1.NotificationTrigger, message string) {
+	if r.Notifications == nil {
+		return
+	}
+	log := logf.FromContext(ctx)
+
+	event := notify.Event{
+		Trigger:          string(trigger),
+		Name:             pd.Name,
+		Namespace:        pd.Namespace,
+		Phase:            pd.Status.Phase,
+		Step:             pd.Status.CurrentStep,
+		CanaryPercentage: pd.Status.CanaryPercentage,
+		Message:          message,
+		Time:             time.Now(),
+	}
+
+	for _, spec := range pd.Spec.Notifications {
+		if len(spec.Triggers) > 0 && !slices.Contains(spec.Triggers, trigger) {
+			continue
+		}
+
+		notifier, err := r.notifierFor(ctx, pd, spec)
+		if err != nil {
+			log.Error(err, "Failed to set up notification", "notification", spec.Name)
+			continue
+		}
+		r.Notifications.Send(ctx, spec.Name, notifier, event)
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+	"github.com/ghanatava/bg-switch/internal/notify"
+)
+
+var _ = Describe("Rollout notifications", func() {
+	var server *httptest.Server
+	var received chan map[string]any
+	var secret *corev1.Secret
+	var cancelDispatcher context.CancelFunc
+	var reconciler *ProgressiveDeploymentReconciler
+
+	BeforeEach(func() {
+		received = make(chan map[string]any, 10)
+		server = httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			payload := map[string]any{}
+			Expect(json.NewDecoder(r.Body).Decode(&payload)).To(Succeed())
+			received <- payload
+		}))
+
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "notify-webhook", Namespace: "default"},
+			Data:       map[string][]byte{notificationURLKey: []byte(server.URL)},
+		}
+		Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+
+		var dispatcherCtx context.Context
+		dispatcherCtx, cancelDispatcher = context.WithCancel(context.Background())
+		dispatcher := notify.NewDispatcher(notify.DefaultQueueSize)
+		go func() {
+			defer GinkgoRecover()
+			Expect(dispatcher.Start(dispatcherCtx)).To(Succeed())
+		}()
+
+		reconciler = &ProgressiveDeploymentReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Notifications: dispatcher}
+	})
+
+	AfterEach(func() {
+		cancelDispatcher()
+		server.Close()
+		Expect(k8sClient.Delete(ctx, secret)).To(Succeed())
+	})
+
+	It("should only send the subscribed triggers", func() {
+		pd := &appsv1alpha1.ProgressiveDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "notify-test", Namespace: "default"},
+			Spec: appsv1alpha1.ProgressiveDeploymentSpec{
+				Notifications: []appsv1alpha1.NotificationSpec{{
+					Name:      "on-call",
+					Type:      notify.TypeSlack,
+					Triggers:  []appsv1alpha1.NotificationTrigger{appsv1alpha1.TriggerRolloutRolledBack},
+					Template:  "{{.Name}}: {{.Message}}",
+					SecretRef: corev1.LocalObjectReference{Name: secret.Name},
+				}},
+			},
+		}
+
+		reconciler.notify(ctx, pd, appsv1alpha1.TriggerRolloutStarted, "")
+		reconciler.notify(ctx, pd, appsv1alpha1.TriggerRolloutRolledBack, "manual rollback")
+
+		Eventually(received).Should(Receive(Equal(map[string]any{"text": "notify-test: manual rollback"})))
+		Consistently(received).ShouldNot(Receive())
+	})
+})
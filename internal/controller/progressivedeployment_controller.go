@@ -19,154 +19,224 @@ package controller
 import (
 	"context"
 	"fmt"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"math"
+	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
-	appsv1 "k8s.io/api/apps/v1"
+	"github.com/ghanatava/bg-switch/internal/notify"
 )
 
+// readinessPollInterval is how often a strategy that is not ready yet is checked again
+const readinessPollInterval = 10 * time.Second
+
 // ProgressiveDeploymentReconciler reconciles a ProgressiveDeployment object
 type ProgressiveDeploymentReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
-}
 
-// updateStatus updates the ProgressiveDeployment status
-func (r *ProgressiveDeploymentReconciler) updateStatus(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) error {
-	return r.Status().Update(ctx, pd)
+	// APIReader reads the ProgressiveDeployment around the cache when a status write
+	// conflicts. Nil reads through Client
+	APIReader client.Reader
+
+	// Notifications delivers spec.notifications in the background. Nil disables notifications
+	Notifications *notify.Dispatcher
+
+	// Recorder records the decisions the controller makes as events. Nil disables events
+	Recorder record.EventRecorder
+
+	// NewMetricsClient returns the querier the analysis measures metrics with.
+	// Nil queries Prometheus with NewPrometheusQuerier
+	NewMetricsClient MetricsClientFactory
+
+	// AnalysisTimeout bounds each metric query of an analysis, a query that does not complete
+	// in time makes the analysis inconclusive. Zero uses DefaultAnalysisTimeout
+	AnalysisTimeout time.Duration
 }
 
-func (r *ProgressiveDeploymentReconciler) getTargetDeployment(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (*appsv1.Deployment, error) {
+// getTargetWorkload fetches the stable workload: the workload referenced by spec.targetRef or
+// spec.targetDeployment, or in GitOps mode the stable workload managed in its place
+func (r *ProgressiveDeploymentReconciler) getTargetWorkload(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (Workload, error) {
 	log := logf.FromContext(ctx)
+	ref := targetRef(pd)
+	if pd.Spec.GitOps {
+		ref.Name = fmt.Sprintf("%s-%s", ref.Name, stableRole)
+	}
 
-	deployment := &appsv1.Deployment{}
-	err := r.Get(ctx, client.ObjectKey{
-		Namespace: pd.Namespace,
-		Name:      pd.Spec.TargetDeployment,
-	}, deployment)
-
+	workload, err := r.getWorkload(ctx, pd, ref.Name)
 	if err != nil {
 		if errors.IsNotFound(err) {
-			log.Error(err, "Target deployment not found", "deployment", pd.Spec.TargetDeployment)
+			log.Error(err, "Target workload not found", "kind", ref.Kind, "name", ref.Name)
 			return nil, err
 		}
-		log.Error(err, "Failed to get target deployment")
+		log.Error(err, "Failed to get target workload")
 		return nil, err
 	}
 
-	log.Info("Found target deployment", "name", deployment.Name, "replicas", *deployment.Spec.Replicas)
-	return deployment, nil
+	log.Info("Found target workload", "kind", ref.Kind, "name", ref.Name, "replicas", workload.Replicas())
+	return workload, nil
 }
 
-// createCanaryDeployment creates a canary Deployment as a clone of the target
-func (r *ProgressiveDeploymentReconciler) createCanaryDeployment(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, targetDeployment *appsv1.Deployment) (*appsv1.Deployment, error) {
+// cloneTarget creates <target>-<role> as a clone of the target whose pods carry
+// the track label set to role, or returns it if it already exists. Scheduling
+// fields set in override replace those of the target's pod template. In GitOps
+// mode the target is the managed stable workload and the clone is made from the
+// workload in Git, which declares the new version
+func (r *ProgressiveDeploymentReconciler) cloneTarget(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, target Workload, role string, replicas int32, override *appsv1alpha1.SchedulingOverride) (Workload, error) {
 	log := logf.FromContext(ctx)
 
-	// Generate canary deployment name
-	canaryName := fmt.Sprintf("%s-canary", pd.Spec.TargetDeployment)
+	// Generate clone name
+	cloneName := fmt.Sprintf("%s-%s", targetRef(pd).Name, role)
 
-	// Clone the target deployment spec
-	canary := &appsv1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      canaryName,
-			Namespace: pd.Namespace,
-			Labels: map[string]string{
-				"app":                    targetDeployment.Labels["app"],
-				"progressive-deployment": pd.Name,
-				"deployment-type":        "canary",
-			},
-		},
-		Spec: *targetDeployment.Spec.DeepCopy(),
+	source := target
+	if pd.Spec.GitOps && role != stableRole {
+		var err error
+		if source, err = r.getSourceWorkload(ctx, pd); err != nil {
+			return nil, err
+		}
 	}
 
-	// Update canary pod labels to differentiate from stable
-	if canary.Spec.Template.Labels == nil {
-		canary.Spec.Template.Labels = make(map[string]string)
+	// Clone the source, keeping its labels and annotations
+	clone := source.Clone(cloneMetadata(pd, source.Object(), cloneName, role))
+	if role == stableRole {
+		// The managed stable runs the same version as the target, it is sized from the annotation
+		delete(clone.Object().GetAnnotations(), replicasAnnotation)
 	}
-	canary.Spec.Template.Labels["version"] = "canary"
-	canary.Spec.Template.Labels["deployment-type"] = "canary"
 
-	// Update selector to match new labels
-	if canary.Spec.Selector == nil {
-		canary.Spec.Selector = &metav1.LabelSelector{}
-	}
-	if canary.Spec.Selector.MatchLabels == nil {
-		canary.Spec.Selector.MatchLabels = make(map[string]string)
+	// Label the clone's pods so its selector never matches the stable pods
+	if err := trackPods(trackLabelKey(pd), role, target.Template(), clone.Template(), clone.Selector()); err != nil {
+		return nil, err
 	}
-	canary.Spec.Selector.MatchLabels["app"] = targetDeployment.Labels["app"]
-	canary.Spec.Selector.MatchLabels["version"] = "canary"
 
-	// Start with 0 replicas - we'll adjust based on canary percentage
-	replicas := int32(0)
-	canary.Spec.Replicas = &replicas
+	// Spread the clone's pods among themselves the way the stable pods are spread
+	rewriteSchedulingSelectors(clone.Template(), target.Template().Labels)
+	applySchedulingOverride(&clone.Template().Spec, override)
+
+	clone.SetReplicas(replicas)
 
-	// Set owner reference so canary gets deleted when ProgressiveDeployment is deleted
-	if err := ctrl.SetControllerReference(pd, canary, r.Scheme); err != nil {
+	// Set owner reference so the clone gets deleted when ProgressiveDeployment is deleted
+	if err := ctrl.SetControllerReference(pd, clone.Object(), r.Scheme); err != nil {
 		log.Error(err, "Failed to set controller reference")
 		return nil, err
 	}
 
-	// Create the canary deployment
-	if err := r.Create(ctx, canary); err != nil {
-		if errors.IsAlreadyExists(err) {
-			log.Info("Canary deployment already exists", "name", canaryName)
-			// Fetch existing canary
-			existingCanary := &appsv1.Deployment{}
-			if err := r.Get(ctx, client.ObjectKey{Namespace: pd.Namespace, Name: canaryName}, existingCanary); err != nil {
-				return nil, err
-			}
-			return existingCanary, nil
+	// Create the clone
+	if err := r.Create(ctx, clone.Object()); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			log.Error(err, "Failed to create workload", "role", role)
+			return nil, err
 		}
-		log.Error(err, "Failed to create canary deployment")
-		return nil, err
+		log.Info("Workload already exists", "name", cloneName)
+		// Fetch existing clone
+		if clone, err = r.getWorkload(ctx, pd, cloneName); err != nil {
+			return nil, err
+		}
+	} else {
+		log.Info("Created workload", "name", cloneName, "role", role, "replicas", replicas)
 	}
 
-	log.Info("Created canary deployment", "name", canaryName, "replicas", 0)
-	return canary, nil
+	setSelectorsCondition(pd, target, clone, role)
+	return clone, nil
+}
+
+// setSelectorsCondition reports in status whether the stable selector also matches the clone's pods
+func setSelectorsCondition(pd *appsv1alpha1.ProgressiveDeployment, target, clone Workload, role string) {
+	condition := metav1.Condition{
+		Type:               appsv1alpha1.ConditionSelectorsDisjoint,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Disjoint",
+		Message:            fmt.Sprintf("The stable and %s selectors cannot select the same pod", role),
+		ObservedGeneration: pd.Generation,
+	}
+	if !selectorsDisjoint(target.Selector(), clone.Selector()) {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Overlapping"
+		condition.Message = fmt.Sprintf("The target's selector also matches the %s pods, add %s to it to keep them apart",
+			role, trackLabelKey(pd))
+	}
+	meta.SetStatusCondition(&pd.Status.Conditions, condition)
+}
+
+// failInitializing marks the ProgressiveDeployment as Failed when the rollout cannot be set up
+func (r *ProgressiveDeploymentReconciler) failInitializing(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, err error) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	pd.Status.Phase = "Failed"
+	pd.Status.HealthStatus = "Unknown"
+	if updateErr := r.updateStatus(ctx, pd); updateErr != nil {
+		// The failure is not recorded, the next reconcile fails again and notifies then
+		log.Error(updateErr, "Failed to update status")
+		return ctrl.Result{}, err
+	}
+	r.notify(ctx, pd, appsv1alpha1.TriggerRolloutFailed, err.Error())
+	return ctrl.Result{}, err
 }
 
-// handleInitializing creates the canary deployment
+// handleInitializing creates the workload running the new version
 func (r *ProgressiveDeploymentReconciler) handleInitializing(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (ctrl.Result, error) {
 	log := logf.FromContext(ctx)
-	log.Info("Handling Initializing phase")
+	log.Info("Handling Initializing phase", "strategy", pd.Spec.Strategy)
 
-	// Step 1: Get the target deployment
-	targetDeployment, err := r.getTargetDeployment(ctx, pd)
+	strategy, err := r.strategyFor(pd)
 	if err != nil {
-		// Update status to Failed
-		pd.Status.Phase = "Failed"
-		pd.Status.HealthStatus = "Unknown"
-		if updateErr := r.updateStatus(ctx, pd); updateErr != nil {
-			log.Error(updateErr, "Failed to update status")
+		return r.failInitializing(ctx, pd, err)
+	}
+	if err := validateTrafficRouting(pd); err != nil {
+		return r.failInitializing(ctx, pd, err)
+	}
+
+	// Step 1: Get the target workload, in GitOps mode the stable workload managed in its place
+	var target Workload
+	if pd.Spec.GitOps {
+		var ready bool
+		target, ready, err = r.ensureManagedStable(ctx, pd)
+		if err != nil {
+			return r.failInitializing(ctx, pd, err)
 		}
-		return ctrl.Result{}, err
+		if !ready {
+			if err := r.updateStatus(ctx, pd); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: readinessPollInterval}, nil
+		}
+	} else if target, err = r.getTargetWorkload(ctx, pd); err != nil {
+		return r.failInitializing(ctx, pd, err)
 	}
 
-	// Step 2: Create canary deployment
-	canary, err := r.createCanaryDeployment(ctx, pd, targetDeployment)
+	// Step 2: Create the new version through the strategy
+	ready, err := strategy.Init(ctx, pd, target)
 	if err != nil {
-		// Update status to Failed
-		pd.Status.Phase = "Failed"
-		pd.Status.HealthStatus = "Unknown"
-		if updateErr := r.updateStatus(ctx, pd); updateErr != nil {
-			log.Error(updateErr, "Failed to update status")
-		}
-		return ctrl.Result{}, err
+		log.Error(err, "Failed to initialize rollout")
+		return r.failInitializing(ctx, pd, err)
+	}
+	if !ready {
+		return ctrl.Result{RequeueAfter: readinessPollInterval}, nil
 	}
 
-	// Step 3: Update status
+	// Step 3: Update status, keeping the step a restarted rollout begins at
+	steps := strategy.Steps(pd)
+	if pd.Status.CurrentStep < 0 || pd.Status.CurrentStep >= len(steps) {
+		pd.Status.CurrentStep = 0
+	}
 	pd.Status.Phase = "Analyzing"
-	pd.Status.CurrentStep = 0
-	pd.Status.CanaryPercentage = pd.Spec.CanarySteps[0]
-	pd.Status.CanaryDeployment = canary.Name
+	pd.Status.CanaryPercentage = steps[pd.Status.CurrentStep]
 	pd.Status.HealthStatus = "Unknown"
+	pd.Status.Plan = nil
+	if err := r.recordObservedSpec(ctx, pd, steps); err != nil {
+		return ctrl.Result{}, err
+	}
 
 	if err := r.updateStatus(ctx, pd); err != nil {
 		return ctrl.Result{}, err
@@ -176,6 +246,7 @@ func (r *ProgressiveDeploymentReconciler) handleInitializing(ctx context.Context
 		"step", pd.Status.CurrentStep,
 		"percentage", pd.Status.CanaryPercentage,
 		"canary", pd.Status.CanaryDeployment)
+	r.notify(ctx, pd, appsv1alpha1.TriggerRolloutStarted, "")
 
 	return ctrl.Result{}, nil
 }
@@ -192,18 +263,34 @@ func (r *ProgressiveDeploymentReconciler) handleAnalyzing(ctx context.Context, p
 	if pd.Status.LastAnalysisTime == nil {
 		log.Info("Starting analysis period", "duration", stepDuration, "canaryPercentage", pd.Status.CanaryPercentage)
 
-		// Get target deployment
-		targetDeployment, err := r.getTargetDeployment(ctx, pd)
+		strategy, err := r.strategyFor(pd)
 		if err != nil {
-			log.Error(err, "Failed to get target deployment for traffic shifting")
 			return ctrl.Result{}, err
 		}
 
+		// Keep the current step while the schedule does not allow traffic shifts
+		if hold, result, err := r.holdOutsideWindow(ctx, pd); hold || err != nil {
+			return result, err
+		}
+
+		// Pin the per-version Services to the revisions being compared,
+		// traffic routers send requests to them
+		ready, err := r.syncVersionServices(ctx, pd)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !ready {
+			return ctrl.Result{RequeueAfter: readinessPollInterval}, nil
+		}
+
 		// Adjust traffic based on current canary percentage
-		if err := r.adjustTraffic(ctx, pd, targetDeployment); err != nil {
-			log.Error(err, "Failed to adjust traffic")
+		ready, err = strategy.ApplyStep(ctx, pd)
+		if err != nil {
 			return ctrl.Result{}, err
 		}
+		if !ready {
+			return ctrl.Result{RequeueAfter: readinessPollInterval}, nil
+		}
 
 		// Set analysis start time
 		pd.Status.LastAnalysisTime = &now
@@ -227,11 +314,8 @@ func (r *ProgressiveDeploymentReconciler) handleAnalyzing(ctx context.Context, p
 	// Enough time passed - analyze metrics and decide
 	log.Info("Analysis period complete", "elapsed", elapsed)
 
-	// TODO: Query Prometheus metrics here
-	// For now, assume healthy
-
 	// Create metrics client
-	metricsClient, err := NewMetricsClient(pd.Spec.Metrics.PrometheusURL)
+	metricsClient, err := r.metricsClient(ctx, pd)
 	if err != nil {
 		log.Error(err, "Failed to create metrics client")
 		pd.Status.Phase = "Failed"
@@ -239,28 +323,74 @@ func (r *ProgressiveDeploymentReconciler) handleAnalyzing(ctx context.Context, p
 		if err := r.updateStatus(ctx, pd); err != nil {
 			return ctrl.Result{}, err
 		}
+		r.notify(ctx, pd, appsv1alpha1.TriggerRolloutFailed, err.Error())
 		return ctrl.Result{}, err
 	}
 
 	// Analyze health using Prometheus metrics
-	healthy, metrics, err := metricsClient.AnalyzeHealth(ctx, pd)
-	if err != nil {
+	result := analyzeHealth(ctx, metricsClient, pd, r.analysisOptions())
+	metrics := measuredMetrics(result)
+
+	// A query that did not complete in time decides nothing, analyze again shortly
+	if result.Inconclusive {
+		pd.Status.InconclusiveAnalyses++
+		pd.Status.Metrics = metrics
+		limit := inconclusiveLimit(pd)
+
+		// A step that stays undecided is not promoted blindly, it is rolled back
+		if pd.Status.InconclusiveAnalyses >= limit {
+			log.Info("❌ Metric analysis INCONCLUSIVE too often - initiating rollback",
+				"inconclusive", pd.Status.InconclusiveAnalyses, "reason", result.Err().Error())
+			pd.Status.Phase = "RollingBack"
+			pd.Status.HealthStatus = "Unknown"
+			pd.Status.LastAnalysisTime = nil
+			pd.Status.RollbackReason = fmt.Sprintf("metric analysis inconclusive %d times in a row at step %d: %v",
+				pd.Status.InconclusiveAnalyses, pd.Status.CurrentStep, result.Err())
+			recordStepResult(pd, false, metrics, result.Err())
+			if err := r.updateStatus(ctx, pd); err != nil {
+				return ctrl.Result{}, err
+			}
+			r.event(pd, corev1.EventTypeWarning, reasonAnalysisInconclusive,
+				"Analysis of step %d was inconclusive %d times in a row, rolling back: %v",
+				pd.Status.CurrentStep, pd.Status.InconclusiveAnalyses, result.Err())
+			return ctrl.Result{}, nil
+		}
+
+		log.Info("⏳ Metric analysis INCONCLUSIVE - analyzing again",
+			"inconclusive", pd.Status.InconclusiveAnalyses, "limit", limit, "reason", result.Err().Error())
+		pd.Status.HealthStatus = "Unknown"
+		if err := r.updateStatus(ctx, pd); err != nil {
+			return ctrl.Result{}, err
+		}
+		r.event(pd, corev1.EventTypeWarning, reasonAnalysisInconclusive,
+			"Analysis of step %d is inconclusive (%d of %d), analyzing again: %v",
+			pd.Status.CurrentStep, pd.Status.InconclusiveAnalyses, limit, result.Err())
+		return ctrl.Result{RequeueAfter: readinessPollInterval}, nil
+	}
+
+	// The analysis decided, the next inconclusive one starts counting again
+	pd.Status.InconclusiveAnalyses = 0
+
+	if err := queryFailures(result); err != nil {
 		// Treat query errors (like "no data") as unhealthy → triggers rollback
 		log.Error(err, "Failed to query metrics - treating as unhealthy, triggering rollback")
-		pd.Status.Phase = "RollingBack" // ← FIXED! Go to RollingBack
+		pd.Status.Phase = "RollingBack"
 		pd.Status.HealthStatus = "Unhealthy"
 		pd.Status.Metrics = metrics
 		pd.Status.LastAnalysisTime = nil
+		pd.Status.RollbackReason = fmt.Sprintf("metric analysis failed at step %d: %v", pd.Status.CurrentStep, err)
+		recordStepResult(pd, false, metrics, err)
 		if err := r.updateStatus(ctx, pd); err != nil {
 			return ctrl.Result{}, err
 		}
-		return ctrl.Result{}, nil // ← Note: return nil error, not err
+		return ctrl.Result{}, nil
 	}
 
 	// Store actual metric values in status
 	pd.Status.Metrics = metrics
+	recordStepResult(pd, result.Healthy, metrics, nil)
 
-	if healthy {
+	if result.Healthy {
 		// Metrics healthy - move to Promoting
 		log.Info("✅ Metrics HEALTHY - proceeding to promotion", "metrics", metrics)
 		pd.Status.Phase = "Promoting"
@@ -272,38 +402,68 @@ func (r *ProgressiveDeploymentReconciler) handleAnalyzing(ctx context.Context, p
 		pd.Status.Phase = "RollingBack"
 		pd.Status.HealthStatus = "Unhealthy"
 		pd.Status.LastAnalysisTime = nil // Reset
+		pd.Status.RollbackReason = fmt.Sprintf("metrics exceeded thresholds at step %d: %v", pd.Status.CurrentStep, metrics)
 	}
 
 	if err := r.updateStatus(ctx, pd); err != nil {
 		return ctrl.Result{}, err
 	}
 
-	log.Info("Metrics healthy, moving to Promoting phase")
 	return ctrl.Result{}, nil
 }
 
-// handlePromoting moves to the next canary step
+// handlePromoting moves to the next step, or finalizes the rollout after the last one
 func (r *ProgressiveDeploymentReconciler) handlePromoting(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (ctrl.Result, error) {
 	log := logf.FromContext(ctx)
 	log.Info("Handling Promoting phase")
 
+	strategy, err := r.strategyFor(pd)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	steps := strategy.Steps(pd)
+
 	// Check if we're at the last step
-	if pd.Status.CurrentStep >= len(pd.Spec.CanarySteps)-1 {
+	if pd.Status.CurrentStep >= len(steps)-1 {
+		// The final switch-over shifts traffic too, unless it already happened
+		if pd.Status.SwitchedAt == nil {
+			if hold, result, err := r.holdOutsideWindow(ctx, pd); hold || err != nil {
+				return result, err
+			}
+		}
+
+		requeueAfter, err := strategy.Finalize(ctx, pd)
+		if err != nil {
+			log.Error(err, "Failed to finalize rollout")
+			return ctrl.Result{}, err
+		}
+		if requeueAfter > 0 {
+			if err := r.updateStatus(ctx, pd); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: requeueAfter}, nil
+		}
+
 		// Deployment complete!
 		log.Info("All steps completed successfully")
 		pd.Status.Phase = "Completed"
 		pd.Status.CanaryPercentage = 100
+		pd.Status.EffectivePercentage = 100
 
+		if err := r.recordRevision(ctx, pd); err != nil {
+			return ctrl.Result{}, err
+		}
 		if err := r.updateStatus(ctx, pd); err != nil {
 			return ctrl.Result{}, err
 		}
+		r.notify(ctx, pd, appsv1alpha1.TriggerRolloutCompleted, "")
 
 		return ctrl.Result{}, nil
 	}
 
 	// Move to next step
 	pd.Status.CurrentStep++
-	pd.Status.CanaryPercentage = pd.Spec.CanarySteps[pd.Status.CurrentStep]
+	pd.Status.CanaryPercentage = steps[pd.Status.CurrentStep]
 	pd.Status.Phase = "Analyzing"
 
 	if err := r.updateStatus(ctx, pd); err != nil {
@@ -311,163 +471,71 @@ func (r *ProgressiveDeploymentReconciler) handlePromoting(ctx context.Context, p
 	}
 
 	log.Info("Promoted to next step", "step", pd.Status.CurrentStep, "percentage", pd.Status.CanaryPercentage)
+	r.notify(ctx, pd, appsv1alpha1.TriggerStepPromoted, "")
 
 	// Requeue to analyze the new step
 	return ctrl.Result{Requeue: true}, nil
 }
 
+// handleRollingBack sends all traffic back to the stable version
 func (r *ProgressiveDeploymentReconciler) handleRollingBack(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (ctrl.Result, error) {
 	log := logf.FromContext(ctx)
 	log.Info("Handling RollingBack phase - restoring stable deployment")
 
-	// Step 1: Get the target (stable) deployment
-	targetDeployment, err := r.getTargetDeployment(ctx, pd)
+	strategy, err := r.strategyFor(pd)
 	if err != nil {
-		log.Error(err, "Failed to get target deployment during rollback")
-		pd.Status.Phase = "Failed"
-		if updateErr := r.updateStatus(ctx, pd); updateErr != nil {
-			log.Error(updateErr, "Failed to update status")
-		}
 		return ctrl.Result{}, err
 	}
 
-	// Step 2: Get the canary deployment
-	canaryDeployment := &appsv1.Deployment{}
-	err = r.Get(ctx, client.ObjectKey{
-		Namespace: pd.Namespace,
-		Name:      pd.Status.CanaryDeployment,
-	}, canaryDeployment)
-
-	if err != nil {
+	if err := strategy.Abort(ctx, pd); err != nil {
 		if errors.IsNotFound(err) {
-			log.Info("Canary deployment already deleted, skipping")
-		} else {
-			log.Error(err, "Failed to get canary deployment during rollback")
-			return ctrl.Result{}, err
+			// The stable deployment is gone, there is nothing left to restore
+			pd.Status.Phase = "Failed"
+			if updateErr := r.updateStatus(ctx, pd); updateErr != nil {
+				log.Error(updateErr, "Failed to update status")
+				return ctrl.Result{}, err
+			}
+			r.notify(ctx, pd, appsv1alpha1.TriggerRolloutFailed, err.Error())
 		}
-	}
-
-	// Step 3: Calculate original total replicas
-	// We need to restore stable to full capacity
-	originalReplicas := *targetDeployment.Spec.Replicas
-	if canaryDeployment != nil && canaryDeployment.Spec.Replicas != nil {
-		// Add current canary replicas to get the total
-		originalReplicas += *canaryDeployment.Spec.Replicas
-	}
-
-	log.Info("Rolling back traffic distribution",
-		"stableReplicas", originalReplicas,
-		"canaryReplicas", 0)
-
-	// Step 4: Restore stable deployment to full replicas
-	targetDeployment.Spec.Replicas = &originalReplicas
-	if err := r.Update(ctx, targetDeployment); err != nil {
-		log.Error(err, "Failed to restore stable deployment replicas")
 		return ctrl.Result{}, err
 	}
-	log.Info("✅ Restored stable deployment to full capacity", "replicas", originalReplicas)
-
-	// Step 5: Scale canary to 0 replicas (if it exists)
-	if canaryDeployment != nil && canaryDeployment.Name != "" {
-		zeroReplicas := int32(0)
-		canaryDeployment.Spec.Replicas = &zeroReplicas
-		if err := r.Update(ctx, canaryDeployment); err != nil {
-			log.Error(err, "Failed to scale down canary deployment")
-			return ctrl.Result{}, err
-		}
-		log.Info("✅ Scaled canary deployment to zero", "name", canaryDeployment.Name)
-	}
 
-	// Step 6: Update status to RolledBack
+	// Update status to RolledBack
 	pd.Status.Phase = "RolledBack"
 	pd.Status.CanaryPercentage = 0
+	pd.Status.EffectivePercentage = 0
 	pd.Status.HealthStatus = "Unhealthy"
+	if pd.Status.RollbackReason == "" {
+		pd.Status.RollbackReason = "manual rollback"
+	}
 
+	if err := r.recordRevision(ctx, pd); err != nil {
+		return ctrl.Result{}, err
+	}
 	if err := r.updateStatus(ctx, pd); err != nil {
 		return ctrl.Result{}, err
 	}
 
 	log.Info("🔄 Rollback completed successfully - stable deployment restored")
+	r.notify(ctx, pd, appsv1alpha1.TriggerRolloutRolledBack, pd.Status.RollbackReason)
 
 	return ctrl.Result{}, nil
 }
 
-// calculateReplicaDistribution calculates stable and canary replica counts
-func calculateReplicaDistribution(totalReplicas int, canaryPercentage int) (stableReplicas, canaryReplicas int32) {
-	if canaryPercentage <= 0 {
-		return int32(totalReplicas), 0
-	}
-
-	if canaryPercentage >= 100 {
-		return 0, int32(totalReplicas)
-	}
-
-	// Calculate canary replicas (round up to ensure traffic gets through)
-	canaryFloat := float64(totalReplicas) * float64(canaryPercentage) / 100.0
-	canaryReplicas = int32(math.Ceil(canaryFloat))
-
-	// Remaining go to stable
-	stableReplicas = int32(totalReplicas) - canaryReplicas
-
-	// Ensure we don't go negative
-	if stableReplicas < 0 {
-		stableReplicas = 0
-	}
-	if canaryReplicas < 0 {
-		canaryReplicas = 0
-	}
-
-	return stableReplicas, canaryReplicas
-}
-
-// adjustTraffic adjusts replica counts for stable and canary deployments
-func (r *ProgressiveDeploymentReconciler) adjustTraffic(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, targetDeployment *appsv1.Deployment) error {
-	log := logf.FromContext(ctx)
-
-	// Get total desired replicas from target
-	totalReplicas := int(*targetDeployment.Spec.Replicas)
-
-	// Calculate distribution
-	stableReplicas, canaryReplicas := calculateReplicaDistribution(totalReplicas, pd.Status.CanaryPercentage)
-
-	log.Info("Calculating traffic distribution",
-		"total", totalReplicas,
-		"canaryPercentage", pd.Status.CanaryPercentage,
-		"stable", stableReplicas,
-		"canary", canaryReplicas)
-
-	// Update stable deployment (target)
-	targetDeployment.Spec.Replicas = &stableReplicas
-	if err := r.Update(ctx, targetDeployment); err != nil {
-		log.Error(err, "Failed to update stable deployment replicas")
-		return err
-	}
-	log.Info("Updated stable deployment", "replicas", stableReplicas)
-
-	// Update canary deployment
-	canaryDeployment := &appsv1.Deployment{}
-	if err := r.Get(ctx, client.ObjectKey{
-		Namespace: pd.Namespace,
-		Name:      pd.Status.CanaryDeployment,
-	}, canaryDeployment); err != nil {
-		log.Error(err, "Failed to get canary deployment")
-		return err
-	}
-
-	canaryDeployment.Spec.Replicas = &canaryReplicas
-	if err := r.Update(ctx, canaryDeployment); err != nil {
-		log.Error(err, "Failed to update canary deployment replicas")
-		return err
-	}
-	log.Info("Updated canary deployment", "replicas", canaryReplicas)
-
-	return nil
-}
-
 // +kubebuilder:rbac:groups=apps.my.domain,resources=progressivedeployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps.my.domain,resources=progressivedeployments/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=apps.my.domain,resources=progressivedeployments/finalizers,verbs=update
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.istio.io,resources=virtualservices;destinationrules,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=apps,resources=replicasets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=controllerrevisions,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
 // TODO(user): Modify the Reconcile function to compare the state specified by
@@ -491,6 +559,8 @@ func (r *ProgressiveDeploymentReconciler) Reconcile(ctx context.Context, req ctr
 		log.Error(err, "Failed to get ProgressiveDeployment")
 		return ctrl.Result{}, err
 	}
+	// Status writes of this reconcile retry conflicts only while nobody else wrote the status
+	ctx = withStatusRead(ctx, &progressiveDeployment)
 	log.Info("Reconciling ProgressiveDeployment",
 		"name", progressiveDeployment.Name,
 		"phase", progressiveDeployment.Status.Phase,
@@ -502,7 +572,14 @@ func (r *ProgressiveDeploymentReconciler) Reconcile(ctx context.Context, req ctr
 		progressiveDeployment.Status.Phase = "Initializing"
 		progressiveDeployment.Status.CurrentStep = 0
 		progressiveDeployment.Status.CanaryPercentage = 0
+		progressiveDeployment.Status.EffectivePercentage = 0
 		progressiveDeployment.Status.HealthStatus = "Unknown"
+		startedAt := metav1.Now()
+		progressiveDeployment.Status.StartedAt = &startedAt
+		// A restartAt already in the past when the resource is created is not a retry request
+		if restartAt := progressiveDeployment.Spec.RestartAt; restartAt != nil && !restartAt.After(startedAt.Time) {
+			progressiveDeployment.Status.RestartedAt = restartAt.DeepCopy()
+		}
 
 		if err := r.updateStatus(ctx, &progressiveDeployment); err != nil {
 			log.Error(err, "Failed to initialize status")
@@ -510,7 +587,50 @@ func (r *ProgressiveDeploymentReconciler) Reconcile(ctx context.Context, req ctr
 		}
 		return ctrl.Result{}, nil
 	}
-	// Step 3: State machine - handle current phase
+	// Plan the rollout instead of running it while spec.dryRun is set
+	if progressiveDeployment.Spec.DryRun {
+		if dryRunApplies(&progressiveDeployment) {
+			return r.handleDryRun(ctx, &progressiveDeployment)
+		}
+		log.Info("Ignoring spec.dryRun while the rollout is in flight", "phase", progressiveDeployment.Status.Phase)
+		r.event(&progressiveDeployment, corev1.EventTypeWarning, reasonDryRunIgnored,
+			"spec.dryRun is ignored while the rollout is %s, it applies once the rollout has finished", progressiveDeployment.Status.Phase)
+	}
+
+	// Mirror the health for Argo CD before acting on it, starting over from the patched version
+	annotated, err := r.syncHealthAnnotations(ctx, &progressiveDeployment)
+	if err != nil {
+		log.Error(err, "Failed to update health annotations")
+		return ctrl.Result{}, err
+	}
+	if annotated {
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	// Apply the spec and template changes made while the rollout is in flight
+	restarted, wrote, err := r.reconcileSpecChanges(ctx, &progressiveDeployment)
+	if err != nil {
+		log.Error(err, "Failed to apply spec changes")
+		return ctrl.Result{}, err
+	}
+	if restarted {
+		return ctrl.Result{}, nil
+	}
+	if wrote {
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	// Step 3: Restart a finished rollout if requested
+	if isTerminalPhase(progressiveDeployment.Status.Phase) {
+		if rollbackToPending(&progressiveDeployment) {
+			return r.handleRollbackTo(ctx, &progressiveDeployment)
+		}
+		if restartPending(&progressiveDeployment) {
+			return r.handleRestart(ctx, &progressiveDeployment)
+		}
+	}
+
+	// Step 4: State machine - handle current phase
 	switch progressiveDeployment.Status.Phase {
 
 	case "Initializing":
@@ -526,7 +646,10 @@ func (r *ProgressiveDeploymentReconciler) Reconcile(ctx context.Context, req ctr
 		return r.handleRollingBack(ctx, &progressiveDeployment)
 
 	case "Completed", "RolledBack", "Failed":
-		// Terminal states - nothing to do
+		// Terminal states - nothing to do, unless Git moved on
+		if progressiveDeployment.Spec.GitOps {
+			return r.handleGitOpsFinished(ctx, &progressiveDeployment)
+		}
 		log.Info("ProgressiveDeployment in terminal state", "phase", progressiveDeployment.Status.Phase)
 		return ctrl.Result{}, nil
 
@@ -541,10 +664,20 @@ func (r *ProgressiveDeploymentReconciler) Reconcile(ctx context.Context, req ctr
 	return ctrl.Result{}, nil
 }
 
+// isTerminalPhase reports whether the state machine has nothing left to do
+func isTerminalPhase(phase string) bool {
+	return phase == "Completed" || phase == "RolledBack" || phase == "Failed"
+}
+
 // SetupWithManager sets up the controller with the Manager.
+// Spec changes of a target workload reconcile the ProgressiveDeployments targeting it
 func (r *ProgressiveDeploymentReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&appsv1alpha1.ProgressiveDeployment{}).
+		Watches(&appsv1.Deployment{}, handler.EnqueueRequestsFromMapFunc(r.requestsForTarget(appsv1alpha1.KindDeployment)),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&appsv1.StatefulSet{}, handler.EnqueueRequestsFromMapFunc(r.requestsForTarget(appsv1alpha1.KindStatefulSet)),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		Named("progressivedeployment").
 		Complete(r)
 }
@@ -0,0 +1,85 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+// restartPending reports whether spec.restartRequest or spec.restartAt asks for a restart
+// the controller has not done yet
+func restartPending(pd *appsv1alpha1.ProgressiveDeployment) bool {
+	return restartRequested(pd) || restartAtPending(pd)
+}
+
+// restartRequested reports whether spec.restartRequest was increased since the last restart
+func restartRequested(pd *appsv1alpha1.ProgressiveDeployment) bool {
+	return pd.Spec.RestartRequest > pd.Status.RestartRequest
+}
+
+// restartAtPending reports whether spec.restartAt is newer than the last restart it caused
+func restartAtPending(pd *appsv1alpha1.ProgressiveDeployment) bool {
+	if pd.Spec.RestartAt == nil {
+		return false
+	}
+	return pd.Status.RestartedAt == nil || pd.Spec.RestartAt.After(pd.Status.RestartedAt.Time)
+}
+
+// handleRestart restarts a finished rollout at spec.restartFromStep, right away for a new
+// spec.restartRequest and once spec.restartAt has passed otherwise.
+// The canary is reused when it still exists, Init re-creates it otherwise
+func (r *ProgressiveDeploymentReconciler) handleRestart(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	if !restartRequested(pd) {
+		if wait := time.Until(pd.Spec.RestartAt.Time); wait > 0 {
+			log.Info("Restart scheduled", "restartAt", pd.Spec.RestartAt, "remaining", wait)
+			return ctrl.Result{RequeueAfter: wait}, nil
+		}
+	}
+
+	strategy, err := r.strategyFor(pd)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	step := pd.Spec.RestartFromStep
+	if last := len(strategy.Steps(pd)) - 1; step > last {
+		log.Info("spec.restartFromStep is past the last step, starting at the last step",
+			"restartFromStep", step, "lastStep", last)
+		step = max(last, 0)
+	}
+
+	log.Info("Restarting rollout", "previousPhase", pd.Status.Phase, "step", step)
+
+	resetRollout(pd, step)
+	pd.Status.RestartRequest = pd.Spec.RestartRequest
+	if restartAtPending(pd) && !pd.Spec.RestartAt.After(time.Now()) {
+		pd.Status.RestartedAt = pd.Spec.RestartAt.DeepCopy()
+	}
+	if err := r.updateStatus(ctx, pd); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+var _ = Describe("Restarting a finished rollout", func() {
+	const resourceName = "restart-test"
+
+	ctx := context.Background()
+	var pd *appsv1alpha1.ProgressiveDeployment
+
+	BeforeEach(func() {
+		pd = &appsv1alpha1.ProgressiveDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"},
+			Spec: appsv1alpha1.ProgressiveDeploymentSpec{
+				TargetDeployment: "restart-app",
+				CanarySteps:      []int{10, 50, 100},
+			},
+		}
+		Expect(k8sClient.Create(ctx, pd)).To(Succeed())
+
+		pd.Status.Phase = "RolledBack"
+		pd.Status.CurrentStep = 1
+		pd.Status.CanaryPercentage = 0
+		pd.Status.HealthStatus = "Unhealthy"
+		pd.Status.RollbackReason = "metrics exceeded thresholds at step 1"
+		Expect(k8sClient.Status().Update(ctx, pd)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(k8sClient.Delete(ctx, pd)).To(Succeed())
+	})
+
+	reconcileOnce := func() reconcile.Result {
+		reconciler := &ProgressiveDeploymentReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		result, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pd)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pd), pd)).To(Succeed())
+		return result
+	}
+
+	It("should reset the status and begin at spec.restartFromStep", func() {
+		restartAt := metav1.NewTime(time.Now().Add(-time.Second))
+		pd.Spec.RestartAt = &restartAt
+		pd.Spec.RestartFromStep = 2
+		Expect(k8sClient.Update(ctx, pd)).To(Succeed())
+
+		reconcileOnce()
+		Expect(pd.Status.Phase).To(Equal("Initializing"))
+		Expect(pd.Status.CurrentStep).To(Equal(2))
+		Expect(pd.Status.HealthStatus).To(Equal("Unknown"))
+		Expect(pd.Status.RollbackReason).To(BeEmpty())
+		Expect(pd.Status.RestartedAt).NotTo(BeNil())
+		Expect(restartPending(pd)).To(BeFalse())
+	})
+
+	It("should wait for a restartAt in the future", func() {
+		restartAt := metav1.NewTime(time.Now().Add(time.Hour))
+		pd.Spec.RestartAt = &restartAt
+		Expect(k8sClient.Update(ctx, pd)).To(Succeed())
+
+		result := reconcileOnce()
+		Expect(result.RequeueAfter).To(BeNumerically(">", 59*time.Minute))
+		Expect(pd.Status.Phase).To(Equal("RolledBack"))
+	})
+
+	It("should restart for every new restartRequest, even within the same second", func() {
+		pd.Spec.RestartRequest = 1
+		Expect(k8sClient.Update(ctx, pd)).To(Succeed())
+
+		reconcileOnce()
+		Expect(pd.Status.Phase).To(Equal("Initializing"))
+		Expect(pd.Status.RestartRequest).To(Equal(int64(1)))
+		Expect(restartPending(pd)).To(BeFalse())
+
+		pd.Status.Phase = "RolledBack"
+		Expect(k8sClient.Status().Update(ctx, pd)).To(Succeed())
+		pd.Spec.RestartRequest = 2
+		Expect(k8sClient.Update(ctx, pd)).To(Succeed())
+
+		reconcileOnce()
+		Expect(pd.Status.Phase).To(Equal("Initializing"))
+		Expect(pd.Status.RestartRequest).To(Equal(int64(2)))
+	})
+
+	It("should keep a future restartAt pending after a restartRequest", func() {
+		restartAt := metav1.NewTime(time.Now().Add(time.Hour))
+		pd.Spec.RestartAt = &restartAt
+		pd.Spec.RestartRequest = 1
+		Expect(k8sClient.Update(ctx, pd)).To(Succeed())
+
+		reconcileOnce()
+		Expect(pd.Status.Phase).To(Equal("Initializing"))
+		Expect(pd.Status.RestartedAt).To(BeNil())
+		Expect(restartAtPending(pd)).To(BeTrue())
+	})
+})
@@ -0,0 +1,178 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+// TrafficRouter sends a share of the requests to the new version for the canary strategy.
+// The canary strategy calls it at each transition:
+//
+//	Analyzing   -> SetWeight (once per step)
+//	Promoting   -> Promote   (after the last step, once the new version runs at full size)
+//	RollingBack -> Abort     (before the stable workload is restored)
+type TrafficRouter interface {
+	// Name identifies the router in logs and validation errors
+	Name() string
+
+	// SetWeight sends weight percent of the requests to the new version
+	SetWeight(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, weight int) error
+
+	// Promote sends all requests to the new version for good and removes what
+	// the router created that is no longer needed
+	Promote(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) error
+
+	// Abort sends all requests back to the stable version and removes what the router created
+	Abort(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) error
+}
+
+// HeaderRouter is a TrafficRouter that can match requests, for setHeaderRoute steps
+type HeaderRouter interface {
+	TrafficRouter
+
+	// SetHeaderRoute sends the requests matching route to the new version, whatever the weight.
+	// Promote and Abort remove the route
+	SetHeaderRoute(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, route *appsv1alpha1.HeaderRoute) error
+}
+
+// MirrorRouter is a TrafficRouter that can mirror requests, for setMirrorRoute steps
+type MirrorRouter interface {
+	TrafficRouter
+
+	// SetMirrorRoute mirrors a percentage of the requests to the new version, discarding its responses.
+	// The next SetWeight, Promote or Abort removes the mirror
+	SetMirrorRoute(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, route *appsv1alpha1.MirrorRoute) error
+}
+
+// routerFor returns the TrafficRouter selected by spec.trafficRouting
+func (r *ProgressiveDeploymentReconciler) routerFor(pd *appsv1alpha1.ProgressiveDeployment) (TrafficRouter, error) {
+	routing := pd.Spec.TrafficRouting
+	switch {
+	case routing == nil:
+		return &replicaRatioRouter{r}, nil
+	case routing.Nginx != nil:
+		return &nginxRouter{r}, nil
+	case routing.Istio != nil:
+		return &istioRouter{r}, nil
+	default:
+		return nil, fmt.Errorf("spec.trafficRouting does not select a router")
+	}
+}
+
+// validateTrafficRouting checks that spec.trafficRouting can be used for this rollout
+func validateTrafficRouting(pd *appsv1alpha1.ProgressiveDeployment) error {
+	if pd.Spec.TrafficRouting == nil {
+		return nil
+	}
+	if pd.Spec.Strategy == appsv1alpha1.StrategyBlueGreen {
+		return fmt.Errorf("spec.trafficRouting is only supported by the canary strategy")
+	}
+	if pd.Spec.Service == "" {
+		return fmt.Errorf("spec.trafficRouting requires spec.service, routers send requests to the <service>-canary Service")
+	}
+	return nil
+}
+
+// validateSteps checks that the router supports every kind of step in spec.steps
+func validateSteps(pd *appsv1alpha1.ProgressiveDeployment, router TrafficRouter) error {
+	for i, step := range pd.Spec.Steps {
+		if step.SetHeaderRoute != nil {
+			if _, ok := router.(HeaderRouter); !ok {
+				return fmt.Errorf("step %d: the %s router does not support setHeaderRoute, use a trafficRouting router that matches requests", i, router.Name())
+			}
+		}
+		if step.SetMirrorRoute != nil {
+			if _, ok := router.(MirrorRouter); !ok {
+				return fmt.Errorf("step %d: the %s router does not support setMirrorRoute, use a trafficRouting router that mirrors requests", i, router.Name())
+			}
+		}
+	}
+	return nil
+}
+
+// replicaRatioRouter shifts traffic by splitting the replicas between stable and canary,
+// so the primary Service balances requests between them in proportion
+type replicaRatioRouter struct {
+	*ProgressiveDeploymentReconciler
+}
+
+// Name returns "replica-ratio"
+func (r *replicaRatioRouter) Name() string {
+	return "replica-ratio"
+}
+
+// SetWeight moves replicas from stable to canary until the canary runs weight percent of them
+func (r *replicaRatioRouter) SetWeight(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, weight int) error {
+	log := logf.FromContext(ctx)
+
+	target, err := r.getTargetWorkload(ctx, pd)
+	if err != nil {
+		return err
+	}
+	canary, err := r.getWorkload(ctx, pd, pd.Status.CanaryDeployment)
+	if err != nil {
+		log.Error(err, "Failed to get canary workload")
+		return err
+	}
+
+	// Total desired replicas is what stable and canary run together, so it
+	// does not shrink as replicas move from stable to canary between steps
+	split := replicaSplitFor(pd)
+	totalReplicas := split.total(target.Replicas(), canary.Replicas())
+
+	// Calculate distribution
+	stableReplicas, canaryReplicas := split.distribute(totalReplicas, weight)
+
+	log.Info("Calculating traffic distribution",
+		"total", totalReplicas,
+		"canaryPercentage", weight,
+		"stable", stableReplicas,
+		"canary", canaryReplicas)
+
+	// Update stable workload (target)
+	if err := r.scaleWorkload(ctx, target, stableReplicas); err != nil {
+		log.Error(err, "Failed to update stable workload replicas")
+		return err
+	}
+	log.Info("Updated stable workload", "replicas", stableReplicas)
+
+	// Update canary workload
+	if err := r.scaleWorkload(ctx, canary, canaryReplicas); err != nil {
+		log.Error(err, "Failed to update canary workload replicas")
+		return err
+	}
+	log.Info("Updated canary workload", "replicas", canaryReplicas)
+
+	// Keep autoscalers from scaling either side out of its share
+	return r.splitAutoscaler(ctx, pd, weight)
+}
+
+// Promote does nothing: the last step already carries the traffic
+func (r *replicaRatioRouter) Promote(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) error {
+	return nil
+}
+
+// Abort does nothing: the canary strategy restores the stable replicas
+func (r *replicaRatioRouter) Abort(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) error {
+	return nil
+}
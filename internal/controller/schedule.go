@@ -0,0 +1,329 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+const (
+	// calendarKey is the ConfigMap key holding a shared RolloutSchedule
+	calendarKey = "schedule"
+
+	// scheduleRecheckInterval bounds how long a held rollout waits before the schedule is evaluated again,
+	// so edits to the schedule or the shared calendar are picked up
+	scheduleRecheckInterval = 15 * time.Minute
+
+	// scheduleLookaheadDays is how far ahead the next allowed time is searched for
+	scheduleLookaheadDays = 400
+)
+
+// weekdays maps ScheduleDay values to time.Weekday
+var weekdays = map[appsv1alpha1.ScheduleDay]time.Weekday{
+	"Sun": time.Sunday,
+	"Mon": time.Monday,
+	"Tue": time.Tuesday,
+	"Wed": time.Wednesday,
+	"Thu": time.Thursday,
+	"Fri": time.Friday,
+	"Sat": time.Saturday,
+}
+
+// compiledWindow is a ScheduleWindow with parsed days and times
+type compiledWindow struct {
+	days     map[time.Weekday]bool
+	start    time.Duration
+	duration time.Duration
+}
+
+// compiledSchedule is a RolloutSchedule ready to be evaluated
+type compiledSchedule struct {
+	location  *time.Location
+	windows   []compiledWindow
+	blackouts []appsv1alpha1.BlackoutRange
+}
+
+// parseClock parses an HH:MM time of day
+func parseClock(value string) (time.Duration, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", value)
+	}
+	return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute, nil
+}
+
+// compileSchedule validates a RolloutSchedule and parses its windows
+func compileSchedule(schedule *appsv1alpha1.RolloutSchedule) (*compiledSchedule, error) {
+	location := time.UTC
+	if schedule.TimeZone != "" {
+		loaded, err := time.LoadLocation(schedule.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time zone %q: %w", schedule.TimeZone, err)
+		}
+		location = loaded
+	}
+
+	compiled := &compiledSchedule{location: location, blackouts: schedule.Blackouts}
+	for _, window := range schedule.Windows {
+		start, err := parseClock(window.Start)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseClock(window.End)
+		if err != nil {
+			return nil, err
+		}
+
+		// An end at or before the start closes the window on the next day
+		duration := end - start
+		if duration <= 0 {
+			duration += 24 * time.Hour
+		}
+
+		days := make(map[time.Weekday]bool, len(window.Days))
+		for _, day := range window.Days {
+			weekday, ok := weekdays[day]
+			if !ok {
+				return nil, fmt.Errorf("invalid day %q", day)
+			}
+			days[weekday] = true
+		}
+
+		compiled.windows = append(compiled.windows, compiledWindow{days: days, start: start, duration: duration})
+	}
+
+	for _, blackout := range schedule.Blackouts {
+		if !blackout.End.After(blackout.Start.Time) {
+			return nil, fmt.Errorf("blackout %q ends before it starts", blackout.Name)
+		}
+	}
+
+	return compiled, nil
+}
+
+// midnight returns the start of the local day t falls on, offset by days
+func midnight(t time.Time, days int) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day()+days, 0, 0, 0, 0, t.Location())
+}
+
+// atClock returns the wall-clock time of day on the local day of day. The time is built
+// from the date rather than added to midnight, so it stays right on daylight saving transitions
+func atClock(day time.Time, clock time.Duration) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, int(clock/time.Second), 0, day.Location())
+}
+
+// bounds returns when the window opens and closes if it opens on day
+func (w compiledWindow) bounds(day time.Time) (time.Time, time.Time) {
+	return atClock(day, w.start), atClock(day, w.start+w.duration)
+}
+
+// allows reports whether traffic may be shifted at t
+func (s *compiledSchedule) allows(t time.Time) bool {
+	for _, blackout := range s.blackouts {
+		if !t.Before(blackout.Start.Time) && t.Before(blackout.End.Time) {
+			return false
+		}
+	}
+
+	if len(s.windows) == 0 {
+		return true
+	}
+
+	local := t.In(s.location)
+	for _, window := range s.windows {
+		// A window that spans midnight may have opened the day before
+		for _, offset := range []int{0, -1} {
+			day := midnight(local, offset)
+			if len(window.days) > 0 && !window.days[day.Weekday()] {
+				continue
+			}
+			opens, closes := window.bounds(day)
+			if !local.Before(opens) && local.Before(closes) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// candidates returns the times after now at which the schedule may start allowing traffic shifts
+func (s *compiledSchedule) candidates(now time.Time) []time.Time {
+	var times []time.Time
+	for _, blackout := range s.blackouts {
+		if blackout.End.After(now) {
+			times = append(times, blackout.End.Time)
+		}
+	}
+
+	local := now.In(s.location)
+	for days := 0; days <= scheduleLookaheadDays; days++ {
+		day := midnight(local, days)
+		for _, window := range s.windows {
+			if len(window.days) > 0 && !window.days[day.Weekday()] {
+				continue
+			}
+			if opens, _ := window.bounds(day); opens.After(now) {
+				times = append(times, opens)
+			}
+		}
+	}
+	return times
+}
+
+// scheduleAllows reports whether every schedule allows traffic shifts at t
+func scheduleAllows(schedules []*compiledSchedule, t time.Time) bool {
+	for _, schedule := range schedules {
+		if !schedule.allows(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// nextAllowedTime returns the first time after now at which every schedule allows traffic shifts
+func nextAllowedTime(schedules []*compiledSchedule, now time.Time) (time.Time, bool) {
+	var candidates []time.Time
+	for _, schedule := range schedules {
+		candidates = append(candidates, schedule.candidates(now)...)
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+
+	for _, candidate := range candidates {
+		if scheduleAllows(schedules, candidate) {
+			return candidate, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// loadCalendar reads the shared schedule referenced by spec.schedule.calendarRef
+func (r *ProgressiveDeploymentReconciler) loadCalendar(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (*appsv1alpha1.RolloutSchedule, error) {
+	ref := pd.Spec.Schedule.CalendarRef
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = pd.Namespace
+	}
+
+	configMap := &corev1.ConfigMap{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ref.Name}, configMap); err != nil {
+		return nil, fmt.Errorf("failed to get calendar %s/%s: %w", namespace, ref.Name, err)
+	}
+
+	data, ok := configMap.Data[calendarKey]
+	if !ok {
+		return nil, fmt.Errorf("calendar %s/%s has no %q key", namespace, ref.Name, calendarKey)
+	}
+
+	calendar := &appsv1alpha1.RolloutSchedule{}
+	if err := yaml.UnmarshalStrict([]byte(data), calendar); err != nil {
+		return nil, fmt.Errorf("invalid calendar %s/%s: %w", namespace, ref.Name, err)
+	}
+	// A shared calendar cannot chain to another one
+	calendar.CalendarRef = nil
+	return calendar, nil
+}
+
+// compileSchedules returns spec.schedule together with the shared calendar it references
+func (r *ProgressiveDeploymentReconciler) compileSchedules(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) ([]*compiledSchedule, error) {
+	sources := []*appsv1alpha1.RolloutSchedule{pd.Spec.Schedule}
+	if pd.Spec.Schedule.CalendarRef != nil {
+		calendar, err := r.loadCalendar(ctx, pd)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, calendar)
+	}
+
+	schedules := make([]*compiledSchedule, 0, len(sources))
+	for _, source := range sources {
+		compiled, err := compileSchedule(source)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, compiled)
+	}
+	return schedules, nil
+}
+
+// holdOutsideWindow keeps the current step while spec.schedule does not allow traffic shifts.
+// It returns true, with the result to return from Reconcile, when the rollout is held.
+// Rollbacks are never held
+func (r *ProgressiveDeploymentReconciler) holdOutsideWindow(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (bool, ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	if pd.Spec.Schedule == nil {
+		meta.RemoveStatusCondition(&pd.Status.Conditions, appsv1alpha1.ConditionOutsideWindow)
+		return false, ctrl.Result{}, nil
+	}
+
+	now := time.Now()
+	reason, message := "", ""
+	requeueAfter := scheduleRecheckInterval
+
+	schedules, err := r.compileSchedules(ctx, pd)
+	switch {
+	case err != nil:
+		// Fail closed: an unreadable freeze calendar must not let traffic through
+		reason, message = "InvalidSchedule", err.Error()
+	case scheduleAllows(schedules, now):
+		meta.SetStatusCondition(&pd.Status.Conditions, metav1.Condition{
+			Type:               appsv1alpha1.ConditionOutsideWindow,
+			Status:             metav1.ConditionFalse,
+			Reason:             "InsideWindow",
+			Message:            "Traffic shifts are allowed by the schedule",
+			ObservedGeneration: pd.Generation,
+		})
+		return false, ctrl.Result{}, nil
+	default:
+		reason = "OutsideWindow"
+		next, found := nextAllowedTime(schedules, now)
+		if found {
+			message = fmt.Sprintf("Holding step %d until the schedule allows traffic shifts at %s",
+				pd.Status.CurrentStep, next.UTC().Format(time.RFC3339))
+			requeueAfter = min(next.Sub(now), scheduleRecheckInterval)
+		} else {
+			message = fmt.Sprintf("Holding step %d, the schedule allows no traffic shifts in the next %d days",
+				pd.Status.CurrentStep, scheduleLookaheadDays)
+		}
+	}
+
+	log.Info("Rollout held by schedule", "reason", reason, "message", message, "requeueAfter", requeueAfter)
+	meta.SetStatusCondition(&pd.Status.Conditions, metav1.Condition{
+		Type:               appsv1alpha1.ConditionOutsideWindow,
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: pd.Generation,
+	})
+	if err := r.updateStatus(ctx, pd); err != nil {
+		return true, ctrl.Result{}, err
+	}
+	return true, ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+var _ = Describe("Schedule", func() {
+	// 2025-06-02 is a Monday
+	at := func(value string) time.Time {
+		t, err := time.Parse(time.RFC3339, value)
+		Expect(err).NotTo(HaveOccurred())
+		return t
+	}
+
+	businessHours := appsv1alpha1.ScheduleWindow{
+		Days:  []appsv1alpha1.ScheduleDay{"Mon", "Tue", "Wed", "Thu", "Fri"},
+		Start: "09:00",
+		End:   "17:00",
+	}
+	overnight := appsv1alpha1.ScheduleWindow{
+		Days:  []appsv1alpha1.ScheduleDay{"Fri"},
+		Start: "22:00",
+		End:   "02:00",
+	}
+	freeze := appsv1alpha1.BlackoutRange{
+		Name:  "release-freeze",
+		Start: metav1.NewTime(time.Date(2025, 6, 3, 0, 0, 0, 0, time.UTC)),
+		End:   metav1.NewTime(time.Date(2025, 6, 5, 0, 0, 0, 0, time.UTC)),
+	}
+
+	DescribeTable("evaluating whether traffic may shift",
+		func(schedule appsv1alpha1.RolloutSchedule, now string, allowed bool) {
+			compiled, err := compileSchedule(&schedule)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(compiled.allows(at(now))).To(Equal(allowed))
+		},
+		Entry("no windows allows any time",
+			appsv1alpha1.RolloutSchedule{}, "2025-06-01T03:00:00Z", true),
+		Entry("inside a weekday window",
+			appsv1alpha1.RolloutSchedule{Windows: []appsv1alpha1.ScheduleWindow{businessHours}}, "2025-06-02T10:00:00Z", true),
+		Entry("window end is exclusive",
+			appsv1alpha1.RolloutSchedule{Windows: []appsv1alpha1.ScheduleWindow{businessHours}}, "2025-06-02T17:00:00Z", false),
+		Entry("weekend is outside a weekday window",
+			appsv1alpha1.RolloutSchedule{Windows: []appsv1alpha1.ScheduleWindow{businessHours}}, "2025-06-01T10:00:00Z", false),
+		Entry("window spanning midnight, after midnight",
+			appsv1alpha1.RolloutSchedule{Windows: []appsv1alpha1.ScheduleWindow{overnight}}, "2025-06-07T01:30:00Z", true),
+		Entry("window spanning midnight, day the window does not open",
+			appsv1alpha1.RolloutSchedule{Windows: []appsv1alpha1.ScheduleWindow{overnight}}, "2025-06-06T01:30:00Z", false),
+		Entry("window evaluated in its time zone",
+			appsv1alpha1.RolloutSchedule{TimeZone: "America/New_York", Windows: []appsv1alpha1.ScheduleWindow{businessHours}}, "2025-06-02T10:00:00Z", false),
+		Entry("blackout overrides a window",
+			appsv1alpha1.RolloutSchedule{Windows: []appsv1alpha1.ScheduleWindow{businessHours}, Blackouts: []appsv1alpha1.BlackoutRange{freeze}}, "2025-06-03T10:00:00Z", false),
+		Entry("blackout without windows",
+			appsv1alpha1.RolloutSchedule{Blackouts: []appsv1alpha1.BlackoutRange{freeze}}, "2025-06-05T00:00:00Z", true),
+	)
+
+	DescribeTable("rejecting invalid schedules",
+		func(schedule appsv1alpha1.RolloutSchedule) {
+			_, err := compileSchedule(&schedule)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("unknown time zone", appsv1alpha1.RolloutSchedule{TimeZone: "Mars/Olympus"}),
+		Entry("invalid time of day", appsv1alpha1.RolloutSchedule{Windows: []appsv1alpha1.ScheduleWindow{{Start: "25:00", End: "10:00"}}}),
+		Entry("blackout ending before it starts", appsv1alpha1.RolloutSchedule{Blackouts: []appsv1alpha1.BlackoutRange{{Name: "x", Start: freeze.End, End: freeze.Start}}}),
+	)
+
+	It("should keep the window times of day on daylight saving transitions", func() {
+		daily := appsv1alpha1.ScheduleWindow{Start: "09:00", End: "17:00"}
+		compiled, err := compileSchedule(&appsv1alpha1.RolloutSchedule{
+			TimeZone: "America/New_York",
+			Windows:  []appsv1alpha1.ScheduleWindow{daily},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		// Clocks moved forward on 2025-03-09 (EDT, -04:00) and back on 2025-11-02 (EST, -05:00)
+		Expect(compiled.allows(at("2025-03-09T09:30:00-04:00"))).To(BeTrue())
+		Expect(compiled.allows(at("2025-03-09T08:30:00-04:00"))).To(BeFalse())
+		Expect(compiled.allows(at("2025-03-09T16:30:00-04:00"))).To(BeTrue())
+		Expect(compiled.allows(at("2025-11-02T08:30:00-05:00"))).To(BeFalse())
+		Expect(compiled.allows(at("2025-11-02T16:30:00-05:00"))).To(BeTrue())
+		Expect(compiled.allows(at("2025-11-02T17:00:00-05:00"))).To(BeFalse())
+
+		next, found := nextAllowedTime([]*compiledSchedule{compiled}, at("2025-03-09T05:00:00-04:00"))
+		Expect(found).To(BeTrue())
+		Expect(next).To(BeTemporally("==", at("2025-03-09T09:00:00-04:00")))
+
+		next, found = nextAllowedTime([]*compiledSchedule{compiled}, at("2025-11-02T05:00:00-05:00"))
+		Expect(found).To(BeTrue())
+		Expect(next).To(BeTemporally("==", at("2025-11-02T09:00:00-05:00")))
+	})
+
+	It("should find the next window after a blackout", func() {
+		compiled, err := compileSchedule(&appsv1alpha1.RolloutSchedule{
+			Windows:   []appsv1alpha1.ScheduleWindow{businessHours},
+			Blackouts: []appsv1alpha1.BlackoutRange{freeze},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		next, found := nextAllowedTime([]*compiledSchedule{compiled}, at("2025-06-02T18:00:00Z"))
+		Expect(found).To(BeTrue())
+		Expect(next).To(Equal(at("2025-06-05T09:00:00Z")))
+	})
+
+	It("should require both the local schedule and the shared calendar", func() {
+		local, err := compileSchedule(&appsv1alpha1.RolloutSchedule{Windows: []appsv1alpha1.ScheduleWindow{businessHours}})
+		Expect(err).NotTo(HaveOccurred())
+		shared, err := compileSchedule(&appsv1alpha1.RolloutSchedule{Blackouts: []appsv1alpha1.BlackoutRange{freeze}})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(scheduleAllows([]*compiledSchedule{local, shared}, at("2025-06-02T10:00:00Z"))).To(BeTrue())
+		Expect(scheduleAllows([]*compiledSchedule{local, shared}, at("2025-06-04T10:00:00Z"))).To(BeFalse())
+	})
+
+	Context("When a shared calendar freezes rollouts", func() {
+		ctx := context.Background()
+		var pd *appsv1alpha1.ProgressiveDeployment
+		var calendar *corev1.ConfigMap
+
+		BeforeEach(func() {
+			calendar = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "change-freeze", Namespace: "default"},
+				Data: map[string]string{
+					calendarKey: "blackouts:\n- name: freeze\n  start: \"2000-01-01T00:00:00Z\"\n  end: \"2999-01-01T00:00:00Z\"\n",
+				},
+			}
+			Expect(k8sClient.Create(ctx, calendar)).To(Succeed())
+
+			pd = &appsv1alpha1.ProgressiveDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "schedule-test", Namespace: "default"},
+				Spec: appsv1alpha1.ProgressiveDeploymentSpec{
+					TargetDeployment: "schedule-app",
+					CanarySteps:      []int{10, 100},
+					Schedule: &appsv1alpha1.RolloutSchedule{
+						CalendarRef: &appsv1alpha1.CalendarReference{Name: calendar.Name},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, pd)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.Delete(ctx, pd)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, calendar)).To(Succeed())
+		})
+
+		It("should hold the step and set the OutsideWindow condition", func() {
+			reconciler := &ProgressiveDeploymentReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+			hold, result, err := reconciler.holdOutsideWindow(ctx, pd)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hold).To(BeTrue())
+			Expect(result.RequeueAfter).To(Equal(scheduleRecheckInterval))
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pd), pd)).To(Succeed())
+			condition := meta.FindStatusCondition(pd.Status.Conditions, appsv1alpha1.ConditionOutsideWindow)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal("OutsideWindow"))
+		})
+
+		It("should fail closed when the calendar is missing", func() {
+			pd.Spec.Schedule.CalendarRef.Name = "missing"
+			reconciler := &ProgressiveDeploymentReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+			hold, _, err := reconciler.holdOutsideWindow(ctx, pd)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hold).To(BeTrue())
+
+			condition := meta.FindStatusCondition(pd.Status.Conditions, appsv1alpha1.ConditionOutsideWindow)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal("InvalidSchedule"))
+		})
+	})
+})
@@ -0,0 +1,147 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+// rewriteSelector makes a selector that matches the stable pods match the clone's pods instead.
+// Requirements on labels whose value differs between the two are moved to the clone's value;
+// selectors that do not match the stable pods are about other workloads and left untouched
+func rewriteSelector(selector *metav1.LabelSelector, stableLabels, cloneLabels map[string]string) {
+	if selector == nil {
+		return
+	}
+	parsed, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil || !parsed.Matches(labels.Set(stableLabels)) {
+		return
+	}
+
+	for key, value := range selector.MatchLabels {
+		cloneValue, ok := cloneLabels[key]
+		switch {
+		case !ok:
+			delete(selector.MatchLabels, key)
+		case value != cloneValue:
+			selector.MatchLabels[key] = cloneValue
+		}
+	}
+
+	expressions := selector.MatchExpressions[:0]
+	for _, requirement := range selector.MatchExpressions {
+		stableValue, stableOK := stableLabels[requirement.Key]
+		cloneValue, cloneOK := cloneLabels[requirement.Key]
+		if stableOK != cloneOK || stableValue != cloneValue {
+			switch requirement.Operator {
+			case metav1.LabelSelectorOpIn:
+				if cloneOK {
+					requirement.Values = []string{cloneValue}
+				} else {
+					requirement.Operator = metav1.LabelSelectorOpDoesNotExist
+					requirement.Values = nil
+				}
+			case metav1.LabelSelectorOpNotIn:
+				requirement.Values = slices.DeleteFunc(requirement.Values, func(value string) bool {
+					return cloneOK && value == cloneValue
+				})
+				if len(requirement.Values) == 0 {
+					continue
+				}
+			case metav1.LabelSelectorOpDoesNotExist:
+				if cloneOK {
+					continue
+				}
+			case metav1.LabelSelectorOpExists:
+				if !cloneOK {
+					requirement.Operator = metav1.LabelSelectorOpDoesNotExist
+				}
+			}
+		}
+		expressions = append(expressions, requirement)
+	}
+	selector.MatchExpressions = expressions
+}
+
+// rewritePodAffinityTerms rewrites the label selectors of pod (anti-)affinity terms
+func rewritePodAffinityTerms(required []corev1.PodAffinityTerm, preferred []corev1.WeightedPodAffinityTerm, stableLabels, cloneLabels map[string]string) {
+	for i := range required {
+		rewriteSelector(required[i].LabelSelector, stableLabels, cloneLabels)
+	}
+	for i := range preferred {
+		rewriteSelector(preferred[i].PodAffinityTerm.LabelSelector, stableLabels, cloneLabels)
+	}
+}
+
+// rewriteSchedulingSelectors makes the pod (anti-)affinity and topology spread constraints of a
+// clone's template that refer to the stable pods refer to the clone's own pods, so its pods are
+// spread among themselves like the stable ones are
+func rewriteSchedulingSelectors(template *corev1.PodTemplateSpec, stableLabels map[string]string) {
+	cloneLabels := template.Labels
+	spec := &template.Spec
+
+	if affinity := spec.Affinity; affinity != nil {
+		if podAffinity := affinity.PodAffinity; podAffinity != nil {
+			rewritePodAffinityTerms(podAffinity.RequiredDuringSchedulingIgnoredDuringExecution,
+				podAffinity.PreferredDuringSchedulingIgnoredDuringExecution, stableLabels, cloneLabels)
+		}
+		if podAntiAffinity := affinity.PodAntiAffinity; podAntiAffinity != nil {
+			rewritePodAffinityTerms(podAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution,
+				podAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution, stableLabels, cloneLabels)
+		}
+	}
+
+	for i := range spec.TopologySpreadConstraints {
+		rewriteSelector(spec.TopologySpreadConstraints[i].LabelSelector, stableLabels, cloneLabels)
+	}
+}
+
+// applySchedulingOverride replaces the scheduling fields of a pod spec that the override sets
+func applySchedulingOverride(spec *corev1.PodSpec, override *appsv1alpha1.SchedulingOverride) {
+	if override == nil {
+		return
+	}
+	if override.NodeSelector != nil {
+		spec.NodeSelector = override.NodeSelector
+	}
+	if override.Tolerations != nil {
+		spec.Tolerations = override.Tolerations
+	}
+	if override.Affinity != nil {
+		spec.Affinity = override.Affinity.DeepCopy()
+	}
+	if override.TopologySpreadConstraints != nil {
+		spec.TopologySpreadConstraints = override.TopologySpreadConstraints
+	}
+	if override.PriorityClassName != "" {
+		spec.PriorityClassName = override.PriorityClassName
+	}
+}
+
+// canaryScheduling returns spec.canary.scheduling, if set
+func canaryScheduling(pd *appsv1alpha1.ProgressiveDeployment) *appsv1alpha1.SchedulingOverride {
+	if pd.Spec.Canary == nil {
+		return nil
+	}
+	return pd.Spec.Canary.Scheduling
+}
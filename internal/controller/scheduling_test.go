@@ -0,0 +1,154 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+var _ = Describe("Canary scheduling", func() {
+	stableLabels := map[string]string{"app": "web", defaultTrackLabelKey: "stable"}
+	canaryLabels := map[string]string{"app": "web", defaultTrackLabelKey: "canary"}
+
+	DescribeTable("rewriting a selector for the canary pods",
+		func(selector, expected *metav1.LabelSelector) {
+			rewriteSelector(selector, stableLabels, canaryLabels)
+			Expect(selector).To(Equal(expected))
+		},
+		Entry("moves the track label in matchLabels",
+			&metav1.LabelSelector{MatchLabels: map[string]string{"app": "web", defaultTrackLabelKey: "stable"}},
+			&metav1.LabelSelector{MatchLabels: map[string]string{"app": "web", defaultTrackLabelKey: "canary"}}),
+		Entry("moves the track label in an In expression",
+			&metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: defaultTrackLabelKey, Operator: metav1.LabelSelectorOpIn, Values: []string{"stable"}},
+			}},
+			&metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: defaultTrackLabelKey, Operator: metav1.LabelSelectorOpIn, Values: []string{"canary"}},
+			}}),
+		Entry("drops a NotIn expression that would exclude the canary",
+			&metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}, MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: defaultTrackLabelKey, Operator: metav1.LabelSelectorOpNotIn, Values: []string{"canary"}},
+			}},
+			&metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}, MatchExpressions: []metav1.LabelSelectorRequirement{}}),
+		Entry("keeps selectors shared by both versions",
+			&metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			&metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}),
+		Entry("leaves selectors for other workloads untouched",
+			&metav1.LabelSelector{MatchLabels: map[string]string{"app": "cache", defaultTrackLabelKey: "stable"}},
+			&metav1.LabelSelector{MatchLabels: map[string]string{"app": "cache", defaultTrackLabelKey: "stable"}}),
+	)
+
+	It("should replace only the scheduling fields the override sets", func() {
+		spec := &corev1.PodSpec{
+			NodeSelector:      map[string]string{"pool": "general"},
+			Tolerations:       []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}},
+			PriorityClassName: "high",
+		}
+		applySchedulingOverride(spec, &appsv1alpha1.SchedulingOverride{NodeSelector: map[string]string{"pool": "canary"}})
+		Expect(spec.NodeSelector).To(Equal(map[string]string{"pool": "canary"}))
+		Expect(spec.Tolerations).To(HaveLen(1))
+		Expect(spec.PriorityClassName).To(Equal("high"))
+
+		applySchedulingOverride(spec, nil)
+		Expect(spec.NodeSelector).To(Equal(map[string]string{"pool": "canary"}))
+	})
+
+	Context("When cloning the target", func() {
+		ctx := context.Background()
+		var pd *appsv1alpha1.ProgressiveDeployment
+		var target *appsv1.Deployment
+
+		BeforeEach(func() {
+			spread := &metav1.LabelSelector{MatchLabels: stableLabels}
+			target = &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: ptr.To[int32](3),
+					Selector: &metav1.LabelSelector{MatchLabels: stableLabels},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: stableLabels},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: "web", Image: "nginx:1.27"}},
+							Affinity: &corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{
+								RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{
+									LabelSelector: spread.DeepCopy(), TopologyKey: "kubernetes.io/hostname",
+								}},
+							}},
+							TopologySpreadConstraints: []corev1.TopologySpreadConstraint{{
+								MaxSkew: 1, TopologyKey: "topology.kubernetes.io/zone",
+								WhenUnsatisfiable: corev1.DoNotSchedule, LabelSelector: spread.DeepCopy(),
+							}},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, target)).To(Succeed())
+
+			pd = &appsv1alpha1.ProgressiveDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "scheduling-test", Namespace: "default"},
+				Spec: appsv1alpha1.ProgressiveDeploymentSpec{
+					TargetDeployment: "web",
+					CanarySteps:      []int{50, 100},
+					Canary: &appsv1alpha1.CanaryOptions{Scheduling: &appsv1alpha1.SchedulingOverride{
+						NodeSelector: map[string]string{"pool": "canary"},
+						Tolerations:  []corev1.Toleration{{Key: "canary", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, pd)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.Delete(ctx, pd)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, target)).To(Succeed())
+			canary := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web-canary", Namespace: "default"}}
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, canary))).To(Succeed())
+		})
+
+		It("should spread the canary pods among themselves and apply the override", func() {
+			reconciler := &ProgressiveDeploymentReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+			workload, err := reconciler.getTargetWorkload(ctx, pd)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = (&canaryStrategy{reconciler}).Init(ctx, pd, workload)
+			Expect(err).NotTo(HaveOccurred())
+
+			canary := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "web-canary"}, canary)).To(Succeed())
+			podSpec := canary.Spec.Template.Spec
+			Expect(podSpec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution[0].LabelSelector.MatchLabels).
+				To(Equal(canaryLabels))
+			Expect(podSpec.TopologySpreadConstraints[0].LabelSelector.MatchLabels).To(Equal(canaryLabels))
+			Expect(podSpec.NodeSelector).To(Equal(map[string]string{"pool": "canary"}))
+			Expect(podSpec.Tolerations).To(HaveLen(1))
+
+			// The target keeps its own scheduling
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(target), target)).To(Succeed())
+			Expect(target.Spec.Template.Spec.TopologySpreadConstraints[0].LabelSelector.MatchLabels).To(Equal(stableLabels))
+			Expect(target.Spec.Template.Spec.NodeSelector).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,196 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+// stableServiceName returns the name of the Service selecting only the stable pods
+func stableServiceName(pd *appsv1alpha1.ProgressiveDeployment) string {
+	return fmt.Sprintf("%s-stable", pd.Spec.Service)
+}
+
+// canaryServiceName returns the name of the Service selecting only the new version's pods
+func canaryServiceName(pd *appsv1alpha1.ProgressiveDeployment) string {
+	return fmt.Sprintf("%s-canary", pd.Spec.Service)
+}
+
+// versionServiceSpec derives the spec of a per-version Service from the primary Service.
+// Addresses allocated to the primary Service are not copied
+func versionServiceSpec(primary *corev1.Service, selector map[string]string) corev1.ServiceSpec {
+	spec := corev1.ServiceSpec{
+		Type:                     primary.Spec.Type,
+		Selector:                 selector,
+		SessionAffinity:          primary.Spec.SessionAffinity,
+		ExternalTrafficPolicy:    primary.Spec.ExternalTrafficPolicy,
+		PublishNotReadyAddresses: primary.Spec.PublishNotReadyAddresses,
+	}
+	for _, port := range primary.Spec.Ports {
+		port.NodePort = 0
+		spec.Ports = append(spec.Ports, port)
+	}
+	return spec
+}
+
+// ensureVersionService creates or updates a Service selecting the pods of one version
+func (r *ProgressiveDeploymentReconciler) ensureVersionService(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, primary *corev1.Service, name, role string, selector map[string]string) error {
+	log := logf.FromContext(ctx)
+
+	annotations := maps.Clone(primary.Annotations)
+	maps.DeleteFunc(annotations, func(key, _ string) bool {
+		return slices.Contains(uncopiedAnnotations, key)
+	})
+	desired := versionServiceSpec(primary, selector)
+
+	existing, err := r.getService(ctx, pd, name)
+	switch {
+	case errors.IsNotFound(err):
+		service := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   pd.Namespace,
+				Annotations: annotations,
+				Labels: map[string]string{
+					"progressive-deployment": pd.Name,
+					"deployment-type":        role,
+				},
+			},
+			Spec: desired,
+		}
+		if err := ctrl.SetControllerReference(pd, service, r.Scheme); err != nil {
+			return err
+		}
+		if err := r.Create(ctx, service); err != nil {
+			return err
+		}
+		log.Info("Created version service", "name", name, "role", role, "selector", selector)
+		return nil
+	case err != nil:
+		return err
+	}
+
+	if !metav1.IsControlledBy(existing, pd) {
+		return fmt.Errorf("service %s already exists and is not managed by this ProgressiveDeployment", name)
+	}
+
+	// Keep the fields the API server allocated and the annotations other writers added
+	updated := existing.DeepCopy()
+	if updated.Annotations == nil {
+		updated.Annotations = map[string]string{}
+	}
+	maps.Copy(updated.Annotations, annotations)
+	updated.Spec.Type = desired.Type
+	updated.Spec.Selector = desired.Selector
+	updated.Spec.SessionAffinity = desired.SessionAffinity
+	updated.Spec.ExternalTrafficPolicy = desired.ExternalTrafficPolicy
+	updated.Spec.PublishNotReadyAddresses = desired.PublishNotReadyAddresses
+	updated.Spec.Ports = desired.Ports
+	for i := range updated.Spec.Ports {
+		for _, current := range existing.Spec.Ports {
+			if current.Name == updated.Spec.Ports[i].Name {
+				updated.Spec.Ports[i].NodePort = current.NodePort
+			}
+		}
+	}
+	if equality.Semantic.DeepEqual(existing, updated) {
+		return nil
+	}
+
+	if err := r.Patch(ctx, updated, client.MergeFrom(existing)); err != nil {
+		return err
+	}
+	log.Info("Updated version service", "name", name, "role", role, "selector", selector)
+	return nil
+}
+
+// pinnedSelector returns the primary Service selector narrowed to the current revision of a workload
+func (r *ProgressiveDeploymentReconciler) pinnedSelector(ctx context.Context, primary *corev1.Service, workload Workload, extra map[string]string) (map[string]string, error) {
+	key, value, err := workload.RevisionLabel(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	selector := maps.Clone(primary.Spec.Selector)
+	if selector == nil {
+		selector = make(map[string]string)
+	}
+	delete(selector, podTemplateHashLabel)
+	delete(selector, controllerRevisionHashLabel)
+	maps.Copy(selector, extra)
+	selector[key] = value
+	return selector, nil
+}
+
+// syncVersionServices points <service>-stable and <service>-canary at the current revision
+// of the stable and new version workloads. It returns false while a revision is not known yet
+func (r *ProgressiveDeploymentReconciler) syncVersionServices(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (bool, error) {
+	if pd.Spec.Service == "" {
+		return true, nil
+	}
+	log := logf.FromContext(ctx)
+
+	primary, err := r.getService(ctx, pd, pd.Spec.Service)
+	if err != nil {
+		log.Error(err, "Failed to get primary service", "service", pd.Spec.Service)
+		return false, err
+	}
+
+	stable, err := r.getTargetWorkload(ctx, pd)
+	if err != nil {
+		return false, err
+	}
+	newVersion, err := r.getWorkload(ctx, pd, pd.Status.CanaryDeployment)
+	if err != nil {
+		return false, err
+	}
+
+	stableSelector, err := r.pinnedSelector(ctx, primary, stable, nil)
+	if err != nil {
+		log.Info("Waiting for stable revision", "reason", err.Error())
+		return false, nil
+	}
+	role := newVersion.Object().GetLabels()["deployment-type"]
+	canarySelector, err := r.pinnedSelector(ctx, primary, newVersion, map[string]string{trackLabelKey(pd): role})
+	if err != nil {
+		log.Info("Waiting for new version revision", "reason", err.Error())
+		return false, nil
+	}
+
+	if err := r.ensureVersionService(ctx, pd, primary, stableServiceName(pd), "stable", stableSelector); err != nil {
+		log.Error(err, "Failed to sync stable service")
+		return false, err
+	}
+	if err := r.ensureVersionService(ctx, pd, primary, canaryServiceName(pd), "canary", canarySelector); err != nil {
+		log.Error(err, "Failed to sync canary service")
+		return false, err
+	}
+	return true, nil
+}
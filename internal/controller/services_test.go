@@ -0,0 +1,157 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+var _ = Describe("Version services", func() {
+	ctx := context.Background()
+	var pd *appsv1alpha1.ProgressiveDeployment
+	var objects []client.Object
+
+	// withReplicaSet creates a Deployment and its current ReplicaSet with the given pod-template-hash
+	withReplicaSet := func(name, role, hash string) {
+		podLabels := map[string]string{"app": "svc-app"}
+		if role != "" {
+			podLabels[defaultTrackLabelKey] = role
+		}
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   "default",
+				UID:         types.UID(name),
+				Labels:      map[string]string{"deployment-type": role},
+				Annotations: map[string]string{revisionAnnotation: "1"},
+			},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: ptr.To[int32](2),
+				Selector: &metav1.LabelSelector{MatchLabels: podLabels},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: podLabels},
+					Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app:v1"}}},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, deployment)).To(Succeed())
+
+		rsLabels := map[string]string{podTemplateHashLabel: hash}
+		for k, v := range podLabels {
+			rsLabels[k] = v
+		}
+		replicaSet := &appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name + "-" + hash,
+				Namespace:   "default",
+				Labels:      rsLabels,
+				Annotations: map[string]string{revisionAnnotation: "1"},
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: "apps/v1", Kind: "Deployment", Name: name, UID: deployment.UID, Controller: ptr.To(true),
+				}},
+			},
+			Spec: appsv1.ReplicaSetSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: rsLabels},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: rsLabels},
+					Spec:       deployment.Spec.Template.Spec,
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, replicaSet)).To(Succeed())
+		objects = append(objects, deployment, replicaSet)
+	}
+
+	BeforeEach(func() {
+		objects = nil
+		primary := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "svc-app",
+				Namespace:   "default",
+				Annotations: map[string]string{"prometheus.io/scrape": "true"},
+			},
+			Spec: corev1.ServiceSpec{
+				Selector: map[string]string{"app": "svc-app"},
+				Ports:    []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt32(8080)}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, primary)).To(Succeed())
+		objects = append(objects, primary)
+
+		withReplicaSet("svc-app", "", "stablehash")
+		withReplicaSet("svc-app-canary", "canary", "canaryhash")
+
+		pd = &appsv1alpha1.ProgressiveDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "services-test", Namespace: "default"},
+			Spec: appsv1alpha1.ProgressiveDeploymentSpec{
+				TargetDeployment: "svc-app",
+				Service:          "svc-app",
+				CanarySteps:      []int{50, 100},
+			},
+		}
+		Expect(k8sClient.Create(ctx, pd)).To(Succeed())
+		pd.Status.CanaryDeployment = "svc-app-canary"
+		objects = append(objects, pd)
+	})
+
+	AfterEach(func() {
+		for _, name := range []string{"svc-app-stable", "svc-app-canary"} {
+			service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, service))).To(Succeed())
+		}
+		for _, object := range objects {
+			Expect(k8sClient.Delete(ctx, object)).To(Succeed())
+		}
+	})
+
+	It("should create stable and canary Services pinned to each revision", func() {
+		reconciler := &ProgressiveDeploymentReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		ready, err := reconciler.syncVersionServices(ctx, pd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ready).To(BeTrue())
+
+		stable := &corev1.Service{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "svc-app-stable"}, stable)).To(Succeed())
+		Expect(stable.Spec.Selector).To(Equal(map[string]string{"app": "svc-app", podTemplateHashLabel: "stablehash"}))
+		Expect(stable.Spec.Ports).To(HaveLen(1))
+		Expect(stable.Annotations).To(HaveKeyWithValue("prometheus.io/scrape", "true"))
+		Expect(metav1.IsControlledBy(stable, pd)).To(BeTrue())
+
+		canary := &corev1.Service{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "svc-app-canary"}, canary)).To(Succeed())
+		Expect(canary.Spec.Selector).To(Equal(map[string]string{
+			"app":                "svc-app",
+			defaultTrackLabelKey: "canary",
+			podTemplateHashLabel: "canaryhash",
+		}))
+
+		// A second sync is a no-op
+		Expect(reconciler.syncVersionServices(ctx, pd)).To(BeTrue())
+	})
+})
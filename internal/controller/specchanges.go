@@ -0,0 +1,216 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+// Reasons of the events recorded for spec changes made while a rollout is in flight
+const (
+	reasonTemplateChanged = "TemplateChanged"
+	reasonStepsChanged    = "StepsChanged"
+	reasonMetricsChanged  = "MetricsChanged"
+)
+
+// event records a Kubernetes event on the ProgressiveDeployment. A nil Recorder disables events
+func (r *ProgressiveDeploymentReconciler) event(pd *appsv1alpha1.ProgressiveDeployment, eventType, reason, messageFmt string, args ...any) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Eventf(pd, eventType, reason, messageFmt, args...)
+}
+
+// inFlight reports whether a rollout is shifting traffic and can still change course.
+// Once a blueGreen rollout has switched over, only the scale-down of blue is left
+func inFlight(pd *appsv1alpha1.ProgressiveDeployment) bool {
+	switch pd.Status.Phase {
+	case "Analyzing":
+		return true
+	case "Promoting":
+		return pd.Status.SwitchedAt == nil
+	}
+	return false
+}
+
+// metricsHash returns a hash of spec.metrics
+func metricsHash(pd *appsv1alpha1.ProgressiveDeployment) string {
+	return computeHash(pd.Spec.Metrics)
+}
+
+// recordObservedSpec remembers the steps, metrics and pod template a rollout starts with,
+// so that later changes to them can be told apart
+func (r *ProgressiveDeploymentReconciler) recordObservedSpec(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, steps []int) error {
+	source, err := r.getSourceWorkload(ctx, pd)
+	if err != nil {
+		return err
+	}
+	pd.Status.ObservedGeneration = pd.Generation
+	pd.Status.ObservedSteps = slices.Clone(steps)
+	pd.Status.ObservedMetricsHash = metricsHash(pd)
+	pd.Status.ObservedTemplateHash = computeTemplateHash(source.Template())
+	return nil
+}
+
+// reconcileSpecChanges applies the policy for changes made while a rollout is in flight:
+// a new pod template on the target restarts the rollout, new steps re-map the current
+// step and new metrics apply from the next analysis. It reports whether the rollout was
+// restarted, and whether the status was written otherwise so the reconcile starts over
+// from the new version. The target is watched, so template changes are seen as they happen
+func (r *ProgressiveDeploymentReconciler) reconcileSpecChanges(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (restarted, wrote bool, err error) {
+	log := logf.FromContext(ctx)
+
+	if !inFlight(pd) {
+		// Changes outside a rollout are picked up when the next one starts. A new pod template
+		// starts one only in GitOps mode, see handleGitOpsFinished: otherwise the target is the
+		// stable workload itself, scaled to zero once a rollout completed, and its template is
+		// not rolled out until the ProgressiveDeployment is recreated
+		if pd.Status.ObservedGeneration == pd.Generation {
+			return false, false, nil
+		}
+		pd.Status.ObservedGeneration = pd.Generation
+		return false, true, r.updateStatus(ctx, pd)
+	}
+
+	strategy, err := r.strategyFor(pd)
+	if err != nil {
+		return false, false, err
+	}
+	steps := strategy.Steps(pd)
+
+	// Rollouts started by an older controller did not record what they started with
+	if pd.Status.ObservedTemplateHash == "" {
+		if err := r.recordObservedSpec(ctx, pd, steps); err != nil {
+			return false, false, err
+		}
+		return false, true, r.updateStatus(ctx, pd)
+	}
+
+	// Step 1: A new pod template on the target restarts the rollout with it
+	source, err := r.getSourceWorkload(ctx, pd)
+	if err != nil {
+		return false, false, err
+	}
+	if computeTemplateHash(source.Template()) != pd.Status.ObservedTemplateHash {
+		return true, false, r.restartForTemplate(ctx, pd, strategy, source)
+	}
+
+	// Step 2: New steps re-map the current step, keeping the canary's traffic where it is
+	changed := pd.Status.ObservedGeneration != pd.Generation
+	if !slices.Equal(steps, pd.Status.ObservedSteps) {
+		r.remapSteps(ctx, pd, steps)
+		changed = true
+	}
+
+	// Step 3: New metrics are read by the next analysis, the current window keeps running
+	if hash := metricsHash(pd); hash != pd.Status.ObservedMetricsHash {
+		log.Info("Metrics changed, applying them at the next analysis")
+		r.event(pd, corev1.EventTypeNormal, reasonMetricsChanged,
+			"spec.metrics changed at step %d, the new queries and thresholds apply from the next analysis", pd.Status.CurrentStep)
+		pd.Status.ObservedMetricsHash = hash
+		changed = true
+	}
+
+	if !changed {
+		return false, false, nil
+	}
+	pd.Status.ObservedGeneration = pd.Generation
+	return false, true, r.updateStatus(ctx, pd)
+}
+
+// remapStep returns the first step whose weight is at least weight, so that new steps never
+// take traffic away from the canary, or the last step when every weight is lower
+func remapStep(steps []int, weight int) int {
+	for i, stepWeight := range steps {
+		if stepWeight >= weight {
+			return i
+		}
+	}
+	return len(steps) - 1
+}
+
+// remapSteps moves the rollout onto new steps. When the step or its weight changes, the
+// step is analyzed again from the start
+func (r *ProgressiveDeploymentReconciler) remapSteps(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, steps []int) {
+	log := logf.FromContext(ctx)
+
+	pd.Status.ObservedSteps = slices.Clone(steps)
+	if len(steps) == 0 {
+		log.Info("Steps changed to none, keeping the current step")
+		r.event(pd, corev1.EventTypeWarning, reasonStepsChanged,
+			"The steps were removed during the rollout, staying at step %d (%d%%)", pd.Status.CurrentStep, pd.Status.CanaryPercentage)
+		return
+	}
+
+	fromStep, fromWeight := pd.Status.CurrentStep, pd.Status.CanaryPercentage
+	toStep := remapStep(steps, fromWeight)
+
+	if toStep == fromStep && steps[toStep] == fromWeight {
+		log.Info("Steps changed, current step is unchanged", "step", fromStep, "percentage", fromWeight)
+		r.event(pd, corev1.EventTypeNormal, reasonStepsChanged,
+			"The steps changed, staying at step %d (%d%%)", fromStep, fromWeight)
+		return
+	}
+
+	pd.Status.CurrentStep = toStep
+	pd.Status.CanaryPercentage = steps[toStep]
+	pd.Status.Phase = "Analyzing"
+	pd.Status.LastAnalysisTime = nil
+
+	log.Info("Steps changed, re-mapped current step",
+		"fromStep", fromStep, "fromPercentage", fromWeight,
+		"toStep", toStep, "toPercentage", steps[toStep])
+	r.event(pd, corev1.EventTypeNormal, reasonStepsChanged,
+		"The steps changed, re-mapped step %d (%d%%) to step %d (%d%%)", fromStep, fromWeight, toStep, steps[toStep])
+}
+
+// restartForTemplate sends traffic back to the stable version, deletes the workload made from
+// the old template and starts the rollout again from the first step
+func (r *ProgressiveDeploymentReconciler) restartForTemplate(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, strategy Strategy, source Workload) error {
+	log := logf.FromContext(ctx)
+	log.Info("Target pod template changed, restarting rollout",
+		"target", source.Object().GetName(),
+		"step", pd.Status.CurrentStep)
+
+	if err := strategy.Abort(ctx, pd); err != nil {
+		log.Error(err, "Failed to restore stable before restarting")
+		return err
+	}
+
+	// Init creates the new version again from the current template
+	newVersion, err := r.getWorkload(ctx, pd, pd.Status.CanaryDeployment)
+	if err == nil {
+		err = r.Delete(ctx, newVersion.Object())
+	}
+	if client.IgnoreNotFound(err) != nil {
+		log.Error(err, "Failed to delete new version workload", "name", pd.Status.CanaryDeployment)
+		return err
+	}
+
+	r.event(pd, corev1.EventTypeNormal, reasonTemplateChanged,
+		"The pod template of %s changed at step %d, restarting the rollout", source.Object().GetName(), pd.Status.CurrentStep)
+
+	resetRollout(pd, 0)
+	return r.updateStatus(ctx, pd)
+}
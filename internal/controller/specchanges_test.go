@@ -0,0 +1,173 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+var _ = Describe("Spec changes during a rollout", func() {
+	DescribeTable("re-mapping the current weight onto new steps",
+		func(steps []int, weight int, expected int) {
+			Expect(remapStep(steps, weight)).To(Equal(expected))
+		},
+		Entry("keeps an unchanged weight", []int{10, 20, 50}, 20, 1),
+		Entry("moves to the next higher weight", []int{5, 25, 50, 100}, 20, 1),
+		Entry("never moves traffic back", []int{30, 60}, 20, 0),
+		Entry("stays at the last step when every weight is lower", []int{5, 10}, 20, 1),
+	)
+
+	Context("When a canary rollout is analyzing", func() {
+		ctx := context.Background()
+		var pd *appsv1alpha1.ProgressiveDeployment
+		var target *appsv1.Deployment
+		var recorder *record.FakeRecorder
+		var reconciler *ProgressiveDeploymentReconciler
+
+		newDeployment := func(name, track string, replicas int32) *appsv1.Deployment {
+			labels := map[string]string{"app": "orders", "track": track}
+			return &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: ptr.To(replicas),
+					Selector: &metav1.LabelSelector{MatchLabels: labels},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: labels},
+						Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "orders", Image: "orders:1.0"}}},
+					},
+				},
+			}
+		}
+
+		applySpecChanges := func() (bool, bool) {
+			restarted, wrote, err := reconciler.reconcileSpecChanges(ctx, pd)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pd), pd)).To(Succeed())
+			return restarted, wrote
+		}
+
+		BeforeEach(func() {
+			target = newDeployment("orders", "stable", 8)
+			Expect(k8sClient.Create(ctx, target)).To(Succeed())
+			Expect(k8sClient.Create(ctx, newDeployment("orders-canary", "canary", 2))).To(Succeed())
+
+			pd = &appsv1alpha1.ProgressiveDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "default"},
+				Spec: appsv1alpha1.ProgressiveDeploymentSpec{
+					TargetDeployment: "orders",
+					CanarySteps:      []int{10, 20, 50},
+					Metrics: appsv1alpha1.MetricsConfig{
+						ErrorRate: appsv1alpha1.MetricThreshold{Query: "errors", Threshold: 0.05},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, pd)).To(Succeed())
+
+			now := metav1.Now()
+			pd.Status.Phase = "Analyzing"
+			pd.Status.CurrentStep = 1
+			pd.Status.CanaryPercentage = 20
+			pd.Status.CanaryDeployment = "orders-canary"
+			pd.Status.LastAnalysisTime = &now
+			Expect(k8sClient.Status().Update(ctx, pd)).To(Succeed())
+
+			recorder = record.NewFakeRecorder(10)
+			reconciler = &ProgressiveDeploymentReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Recorder: recorder}
+
+			// The first pass records what the rollout is running
+			restarted, wrote := applySpecChanges()
+			Expect(restarted).To(BeFalse())
+			Expect(wrote).To(BeTrue())
+			Expect(pd.Status.ObservedSteps).To(Equal([]int{10, 20, 50}))
+			Expect(pd.Status.ObservedTemplateHash).NotTo(BeEmpty())
+			Expect(pd.Status.ObservedGeneration).To(Equal(pd.Generation))
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.Delete(ctx, pd)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, target)).To(Succeed())
+			canary := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "orders-canary", Namespace: "default"}}
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, canary))).To(Succeed())
+		})
+
+		It("should re-map the current step onto new steps and analyze it again", func() {
+			pd.Spec.CanarySteps = []int{5, 25, 50, 100}
+			Expect(k8sClient.Update(ctx, pd)).To(Succeed())
+
+			restarted, wrote := applySpecChanges()
+			Expect(restarted).To(BeFalse())
+			Expect(wrote).To(BeTrue())
+			Expect(pd.Status.Phase).To(Equal("Analyzing"))
+			Expect(pd.Status.CurrentStep).To(Equal(1))
+			Expect(pd.Status.CanaryPercentage).To(Equal(25))
+			Expect(pd.Status.LastAnalysisTime).To(BeNil())
+			Expect(pd.Status.ObservedSteps).To(Equal([]int{5, 25, 50, 100}))
+			Expect(pd.Status.ObservedGeneration).To(Equal(pd.Generation))
+			Expect(recorder.Events).To(Receive(ContainSubstring("StepsChanged")))
+		})
+
+		It("should apply new metrics at the next analysis", func() {
+			pd.Spec.Metrics.ErrorRate.Threshold = 0.01
+			Expect(k8sClient.Update(ctx, pd)).To(Succeed())
+
+			restarted, wrote := applySpecChanges()
+			Expect(restarted).To(BeFalse())
+			Expect(wrote).To(BeTrue())
+			Expect(pd.Status.CurrentStep).To(Equal(1))
+			Expect(pd.Status.LastAnalysisTime).NotTo(BeNil())
+			Expect(pd.Status.ObservedMetricsHash).To(Equal(metricsHash(pd)))
+			Expect(recorder.Events).To(Receive(ContainSubstring("MetricsChanged")))
+		})
+
+		It("should restart the rollout when the target's pod template changes", func() {
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(target), target)).To(Succeed())
+			target.Spec.Template.Spec.Containers[0].Image = "orders:2.0"
+			Expect(k8sClient.Update(ctx, target)).To(Succeed())
+
+			restarted, _ := applySpecChanges()
+			Expect(restarted).To(BeTrue())
+			Expect(pd.Status.Phase).To(Equal("Initializing"))
+			Expect(pd.Status.CurrentStep).To(Equal(0))
+			Expect(recorder.Events).To(Receive(ContainSubstring("TemplateChanged")))
+
+			canary := &appsv1.Deployment{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "orders-canary"}, canary)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should leave a rollout alone when nothing it depends on changed", func() {
+			restarted, wrote := applySpecChanges()
+			Expect(restarted).To(BeFalse())
+			Expect(wrote).To(BeFalse())
+			Expect(pd.Status.CurrentStep).To(Equal(1))
+			Expect(pd.Status.LastAnalysisTime).NotTo(BeNil())
+			Expect(recorder.Events).To(BeEmpty())
+		})
+	})
+})
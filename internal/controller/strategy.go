@@ -0,0 +1,70 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+// Strategy implements the workload and traffic side of a rollout for one spec.strategy.
+// The phase handlers own the state machine and call into the strategy at each transition:
+//
+//	Initializing -> Init
+//	Analyzing    -> ApplyStep (once per step, before the analysis window starts)
+//	Promoting    -> Finalize  (after the last step)
+//	RollingBack  -> Abort
+//
+// Strategies record what they need to resume in pd.Status; the caller persists it.
+type Strategy interface {
+	// Steps returns the traffic percentages the rollout walks through, one analysis per step
+	Steps(pd *appsv1alpha1.ProgressiveDeployment) []int
+
+	// Init creates the workload running the new version and sets status.canaryDeployment.
+	// It returns false when it has to be called again later.
+	Init(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, target Workload) (bool, error)
+
+	// ApplyStep shifts traffic to status.canaryPercentage.
+	// It returns false while the new version is not ready for the analysis window to start.
+	ApplyStep(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (bool, error)
+
+	// Finalize makes the new version the only one serving traffic.
+	// It returns a non-zero duration when it has to be called again after that delay.
+	Finalize(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) (time.Duration, error)
+
+	// Abort sends all traffic back to the stable version and scales the new version to zero
+	Abort(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment) error
+
+	// Plan returns the replica split of each step for a target of the given size.
+	// It reads nothing from the cluster and changes nothing (spec.dryRun)
+	Plan(pd *appsv1alpha1.ProgressiveDeployment, replicas int32) []appsv1alpha1.PlannedStep
+}
+
+// strategyFor returns the Strategy selected by spec.strategy
+func (r *ProgressiveDeploymentReconciler) strategyFor(pd *appsv1alpha1.ProgressiveDeployment) (Strategy, error) {
+	switch pd.Spec.Strategy {
+	case "", appsv1alpha1.StrategyCanary:
+		return &canaryStrategy{r}, nil
+	case appsv1alpha1.StrategyBlueGreen:
+		return &blueGreenStrategy{r}, nil
+	default:
+		return nil, fmt.Errorf("unknown strategy %q", pd.Spec.Strategy)
+	}
+}
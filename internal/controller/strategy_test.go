@@ -0,0 +1,109 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/ptr"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+var _ = Describe("Strategy selection", func() {
+	reconciler := &ProgressiveDeploymentReconciler{}
+
+	DescribeTable("should pick the strategy from spec.strategy",
+		func(name string, expected Strategy, steps []int) {
+			pd := &appsv1alpha1.ProgressiveDeployment{
+				Spec: appsv1alpha1.ProgressiveDeploymentSpec{
+					Strategy:    name,
+					CanarySteps: []int{25, 50, 100},
+				},
+			}
+			strategy, err := reconciler.strategyFor(pd)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(strategy).To(BeAssignableToTypeOf(expected))
+			Expect(strategy.Steps(pd)).To(Equal(steps))
+		},
+		Entry("defaults to canary", "", &canaryStrategy{}, []int{25, 50, 100}),
+		Entry("canary", appsv1alpha1.StrategyCanary, &canaryStrategy{}, []int{25, 50, 100}),
+		Entry("blueGreen", appsv1alpha1.StrategyBlueGreen, &blueGreenStrategy{}, []int{0}),
+	)
+
+	It("should derive step weights and the active header route from spec.steps", func() {
+		route := &appsv1alpha1.HeaderRoute{Header: &appsv1alpha1.RequestMatch{Name: "X-Canary", Value: "true"}}
+		pd := &appsv1alpha1.ProgressiveDeployment{
+			Spec: appsv1alpha1.ProgressiveDeploymentSpec{
+				Steps: []appsv1alpha1.CanaryStep{
+					{SetHeaderRoute: route},
+					{SetWeight: ptr.To(20)},
+					{SetWeight: ptr.To(100)},
+				},
+			},
+		}
+		Expect((&canaryStrategy{reconciler}).Steps(pd)).To(Equal([]int{0, 20, 100}))
+		Expect(activeHeaderRoute(pd)).To(Equal(route))
+
+		// The header route stays in place during the weighted steps
+		pd.Status.CurrentStep = 2
+		Expect(activeHeaderRoute(pd)).To(Equal(route))
+
+		pd.Spec.Steps = pd.Spec.Steps[1:]
+		Expect(activeHeaderRoute(pd)).To(BeNil())
+	})
+
+	It("should reject setHeaderRoute steps for routers that cannot match requests", func() {
+		pd := &appsv1alpha1.ProgressiveDeployment{
+			Spec: appsv1alpha1.ProgressiveDeploymentSpec{
+				Steps: []appsv1alpha1.CanaryStep{
+					{SetHeaderRoute: &appsv1alpha1.HeaderRoute{Cookie: &appsv1alpha1.RequestMatch{Name: "canary", Value: "always"}}},
+				},
+			},
+		}
+		Expect(validateSteps(pd, &replicaRatioRouter{reconciler})).To(MatchError(ContainSubstring("replica-ratio router does not support setHeaderRoute")))
+		Expect(validateSteps(pd, &nginxRouter{reconciler})).To(Succeed())
+		Expect(validateSteps(pd, &istioRouter{reconciler})).To(Succeed())
+	})
+
+	It("should reject setMirrorRoute steps for routers that cannot mirror requests", func() {
+		pd := &appsv1alpha1.ProgressiveDeployment{
+			Spec: appsv1alpha1.ProgressiveDeploymentSpec{
+				Steps: []appsv1alpha1.CanaryStep{
+					{SetMirrorRoute: &appsv1alpha1.MirrorRoute{Percentage: 20}},
+					{SetWeight: ptr.To(100)},
+				},
+			},
+		}
+		Expect(validateSteps(pd, &replicaRatioRouter{reconciler})).To(MatchError(ContainSubstring("replica-ratio router does not support setMirrorRoute")))
+		Expect(validateSteps(pd, &nginxRouter{reconciler})).To(MatchError(ContainSubstring("nginx router does not support setMirrorRoute")))
+		Expect(validateSteps(pd, &istioRouter{reconciler})).To(Succeed())
+
+		// The mirror only lasts for its own step
+		Expect(activeMirrorRoute(pd)).NotTo(BeNil())
+		pd.Status.CurrentStep = 1
+		Expect(activeMirrorRoute(pd)).To(BeNil())
+	})
+
+	It("should reject an unknown strategy", func() {
+		pd := &appsv1alpha1.ProgressiveDeployment{
+			Spec: appsv1alpha1.ProgressiveDeploymentSpec{Strategy: "shadow"},
+		}
+		_, err := reconciler.strategyFor(pd)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,240 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+const (
+	// podTemplateHashLabel is added by the Deployment controller to every ReplicaSet and its pods
+	podTemplateHashLabel = "pod-template-hash"
+
+	// controllerRevisionHashLabel is added by the StatefulSet controller to its pods
+	controllerRevisionHashLabel = "controller-revision-hash"
+
+	// revisionAnnotation holds the rollout revision of a Deployment and its ReplicaSets
+	revisionAnnotation = "deployment.kubernetes.io/revision"
+)
+
+// Workload adapts a Deployment or a StatefulSet, so strategies can clone, scale and
+// wait for a workload without knowing its kind
+type Workload interface {
+	// Object returns the underlying object for use with the client
+	Object() client.Object
+
+	// Replicas returns the desired number of replicas
+	Replicas() int32
+
+	// SetReplicas changes the desired number of replicas in memory, see scaleWorkload
+	SetReplicas(replicas int32)
+
+	// Template returns the pod template, changes to it are kept in the object
+	Template() *corev1.PodTemplateSpec
+
+	// Selector returns the pod selector, changes to it are kept in the object
+	Selector() *metav1.LabelSelector
+
+	// Available reports whether the latest spec was observed and every desired replica is updated and available
+	Available() bool
+
+	// AvailableReplicas returns the number of available replicas
+	AvailableReplicas() int32
+
+	// Clone returns a new, unsaved workload of the same kind with a copy of the spec
+	Clone(meta metav1.ObjectMeta) Workload
+
+	// RevisionLabel returns the label key and value carried only by the pods of the current revision
+	RevisionLabel(ctx context.Context, c client.Reader) (string, string, error)
+}
+
+// targetRef returns spec.targetRef, or the Deployment named by spec.targetDeployment
+func targetRef(pd *appsv1alpha1.ProgressiveDeployment) appsv1alpha1.WorkloadRef {
+	if pd.Spec.TargetRef != nil {
+		return *pd.Spec.TargetRef
+	}
+	return appsv1alpha1.WorkloadRef{APIVersion: "apps/v1", Kind: appsv1alpha1.KindDeployment, Name: pd.Spec.TargetDeployment}
+}
+
+// requestsForTarget maps a workload of the given kind to the ProgressiveDeployments targeting it
+func (r *ProgressiveDeploymentReconciler) requestsForTarget(kind string) handler.MapFunc {
+	return func(ctx context.Context, object client.Object) []reconcile.Request {
+		list := &appsv1alpha1.ProgressiveDeploymentList{}
+		if err := r.List(ctx, list, client.InNamespace(object.GetNamespace())); err != nil {
+			logf.FromContext(ctx).Error(err, "Failed to list ProgressiveDeployments", "workload", object.GetName())
+			return nil
+		}
+
+		var requests []reconcile.Request
+		for i := range list.Items {
+			ref := targetRef(&list.Items[i])
+			if ref.Name != object.GetName() {
+				continue
+			}
+			if ref.Kind == kind || (ref.Kind == "" && kind == appsv1alpha1.KindDeployment) {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&list.Items[i])})
+			}
+		}
+		return requests
+	}
+}
+
+// newWorkload returns an empty Workload of the given kind
+func newWorkload(kind string) (Workload, error) {
+	switch kind {
+	case "", appsv1alpha1.KindDeployment:
+		return &deploymentWorkload{&appsv1.Deployment{}}, nil
+	case appsv1alpha1.KindStatefulSet:
+		return &statefulSetWorkload{&appsv1.StatefulSet{}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported workload kind %q", kind)
+	}
+}
+
+// getWorkload fetches a workload of the target's kind in the ProgressiveDeployment namespace
+func (r *ProgressiveDeploymentReconciler) getWorkload(ctx context.Context, pd *appsv1alpha1.ProgressiveDeployment, name string) (Workload, error) {
+	workload, err := newWorkload(targetRef(pd).Kind)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: pd.Namespace, Name: name}, workload.Object()); err != nil {
+		return nil, err
+	}
+	return workload, nil
+}
+
+// isAvailable reports whether generation was observed and desired replicas are updated and available
+func isAvailable(generation, observedGeneration int64, desired, updated, available int32) bool {
+	return observedGeneration >= generation && updated >= desired && available >= desired
+}
+
+// deploymentWorkload adapts an appsv1.Deployment
+type deploymentWorkload struct {
+	*appsv1.Deployment
+}
+
+func (w *deploymentWorkload) Object() client.Object { return w.Deployment }
+
+func (w *deploymentWorkload) Replicas() int32 {
+	if w.Spec.Replicas == nil {
+		return 1
+	}
+	return *w.Spec.Replicas
+}
+
+func (w *deploymentWorkload) SetReplicas(replicas int32) { w.Spec.Replicas = &replicas }
+
+func (w *deploymentWorkload) Template() *corev1.PodTemplateSpec { return &w.Spec.Template }
+
+func (w *deploymentWorkload) Selector() *metav1.LabelSelector {
+	if w.Spec.Selector == nil {
+		w.Spec.Selector = &metav1.LabelSelector{}
+	}
+	return w.Spec.Selector
+}
+
+func (w *deploymentWorkload) Available() bool {
+	return isAvailable(w.Generation, w.Status.ObservedGeneration, w.Replicas(), w.Status.UpdatedReplicas, w.Status.AvailableReplicas)
+}
+
+func (w *deploymentWorkload) AvailableReplicas() int32 { return w.Status.AvailableReplicas }
+
+func (w *deploymentWorkload) Clone(meta metav1.ObjectMeta) Workload {
+	return &deploymentWorkload{&appsv1.Deployment{ObjectMeta: meta, Spec: *w.Spec.DeepCopy()}}
+}
+
+// RevisionLabel returns the pod-template-hash of the current ReplicaSet
+func (w *deploymentWorkload) RevisionLabel(ctx context.Context, c client.Reader) (string, string, error) {
+	selector, err := metav1.LabelSelectorAsSelector(w.Spec.Selector)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid selector on deployment %s: %w", w.Name, err)
+	}
+
+	replicaSets := &appsv1.ReplicaSetList{}
+	if err := c.List(ctx, replicaSets,
+		client.InNamespace(w.Namespace),
+		client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return "", "", err
+	}
+
+	revision := w.Annotations[revisionAnnotation]
+	for _, rs := range replicaSets.Items {
+		if !metav1.IsControlledBy(&rs, w.Deployment) {
+			continue
+		}
+		if rs.Annotations[revisionAnnotation] == revision && rs.Labels[podTemplateHashLabel] != "" {
+			return podTemplateHashLabel, rs.Labels[podTemplateHashLabel], nil
+		}
+	}
+
+	return "", "", fmt.Errorf("no current ReplicaSet found for deployment %s", w.Name)
+}
+
+// statefulSetWorkload adapts an appsv1.StatefulSet
+type statefulSetWorkload struct {
+	*appsv1.StatefulSet
+}
+
+func (w *statefulSetWorkload) Object() client.Object { return w.StatefulSet }
+
+func (w *statefulSetWorkload) Replicas() int32 {
+	if w.Spec.Replicas == nil {
+		return 1
+	}
+	return *w.Spec.Replicas
+}
+
+func (w *statefulSetWorkload) SetReplicas(replicas int32) { w.Spec.Replicas = &replicas }
+
+func (w *statefulSetWorkload) Template() *corev1.PodTemplateSpec { return &w.Spec.Template }
+
+func (w *statefulSetWorkload) Selector() *metav1.LabelSelector {
+	if w.Spec.Selector == nil {
+		w.Spec.Selector = &metav1.LabelSelector{}
+	}
+	return w.Spec.Selector
+}
+
+func (w *statefulSetWorkload) Available() bool {
+	return isAvailable(w.Generation, w.Status.ObservedGeneration, w.Replicas(), w.Status.UpdatedReplicas, w.Status.AvailableReplicas)
+}
+
+func (w *statefulSetWorkload) AvailableReplicas() int32 { return w.Status.AvailableReplicas }
+
+// Clone copies the spec; the clone keeps the governing Service and gets its own volume claims
+func (w *statefulSetWorkload) Clone(meta metav1.ObjectMeta) Workload {
+	return &statefulSetWorkload{&appsv1.StatefulSet{ObjectMeta: meta, Spec: *w.Spec.DeepCopy()}}
+}
+
+// RevisionLabel returns the controller-revision-hash of the current revision
+func (w *statefulSetWorkload) RevisionLabel(_ context.Context, _ client.Reader) (string, string, error) {
+	if w.Status.CurrentRevision == "" {
+		return "", "", fmt.Errorf("no current revision found for statefulset %s", w.Name)
+	}
+	return controllerRevisionHashLabel, w.Status.CurrentRevision, nil
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1alpha1 "github.com/ghanatava/bg-switch/api/v1alpha1"
+)
+
+var _ = Describe("Workload", func() {
+	It("should default the target to the Deployment in spec.targetDeployment", func() {
+		pd := &appsv1alpha1.ProgressiveDeployment{Spec: appsv1alpha1.ProgressiveDeploymentSpec{TargetDeployment: "web"}}
+		Expect(targetRef(pd)).To(Equal(appsv1alpha1.WorkloadRef{APIVersion: "apps/v1", Kind: appsv1alpha1.KindDeployment, Name: "web"}))
+
+		pd.Spec.TargetRef = &appsv1alpha1.WorkloadRef{APIVersion: "apps/v1", Kind: appsv1alpha1.KindStatefulSet, Name: "db"}
+		Expect(targetRef(pd).Name).To(Equal("db"))
+	})
+
+	It("should reject unsupported kinds", func() {
+		_, err := newWorkload("DaemonSet")
+		Expect(err).To(MatchError(ContainSubstring("unsupported workload kind")))
+	})
+
+	It("should report availability only once every desired replica is updated", func() {
+		workload := &statefulSetWorkload{&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Generation: 2},
+			Spec:       appsv1.StatefulSetSpec{Replicas: ptr.To[int32](3)},
+			Status:     appsv1.StatefulSetStatus{ObservedGeneration: 2, UpdatedReplicas: 2, AvailableReplicas: 3},
+		}}
+		Expect(workload.Available()).To(BeFalse())
+
+		workload.Status.UpdatedReplicas = 3
+		Expect(workload.Available()).To(BeTrue())
+	})
+
+	It("should select StatefulSet pods by controller-revision-hash", func() {
+		workload := &statefulSetWorkload{&appsv1.StatefulSet{Status: appsv1.StatefulSetStatus{CurrentRevision: "db-5f8d7c"}}}
+		key, value, err := workload.RevisionLabel(context.Background(), k8sClient)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key).To(Equal(controllerRevisionHashLabel))
+		Expect(value).To(Equal("db-5f8d7c"))
+	})
+
+	Context("When the target is a StatefulSet", func() {
+		ctx := context.Background()
+		var pd *appsv1alpha1.ProgressiveDeployment
+		var target *appsv1.StatefulSet
+
+		BeforeEach(func() {
+			target = &appsv1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default", Labels: map[string]string{"app": "db"}},
+				Spec: appsv1.StatefulSetSpec{
+					Replicas:    ptr.To[int32](4),
+					ServiceName: "db",
+					Selector:    &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "db"}},
+						Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "db", Image: "postgres:17"}}},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, target)).To(Succeed())
+
+			pd = &appsv1alpha1.ProgressiveDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "workload-test", Namespace: "default"},
+				Spec: appsv1alpha1.ProgressiveDeploymentSpec{
+					TargetRef:   &appsv1alpha1.WorkloadRef{APIVersion: "apps/v1", Kind: appsv1alpha1.KindStatefulSet, Name: "db"},
+					CanarySteps: []int{25, 100},
+				},
+			}
+			Expect(k8sClient.Create(ctx, pd)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.Delete(ctx, pd)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, target)).To(Succeed())
+			canary := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "db-canary", Namespace: "default"}}
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, canary))).To(Succeed())
+		})
+
+		It("should clone a canary StatefulSet and split replicas through the adapter", func() {
+			reconciler := &ProgressiveDeploymentReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+			strategy := &canaryStrategy{reconciler}
+
+			workload, err := reconciler.getTargetWorkload(ctx, pd)
+			Expect(err).NotTo(HaveOccurred())
+			ready, err := strategy.Init(ctx, pd, workload)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ready).To(BeTrue())
+			Expect(pd.Status.CanaryDeployment).To(Equal("db-canary"))
+
+			pd.Status.CanaryPercentage = 25
+			Expect(strategy.ApplyStep(ctx, pd)).To(BeTrue())
+
+			canary := &appsv1.StatefulSet{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "db-canary"}, canary)).To(Succeed())
+			Expect(*canary.Spec.Replicas).To(Equal(int32(1)))
+			Expect(canary.Spec.Selector.MatchLabels).To(HaveKeyWithValue(defaultTrackLabelKey, "canary"))
+			Expect(canary.Spec.ServiceName).To(Equal("db"))
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(target), target)).To(Succeed())
+			Expect(*target.Spec.Replicas).To(Equal(int32(3)))
+		})
+	})
+})
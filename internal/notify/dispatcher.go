@@ -0,0 +1,158 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notify
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// DefaultQueueSize is the number of notifications buffered before new ones are dropped
+	DefaultQueueSize = 100
+
+	// DefaultWorkers is the number of notifications sent at once
+	DefaultWorkers = 4
+
+	// DefaultAttempts is how many times a notification is sent before it is given up
+	DefaultAttempts = 4
+
+	// DefaultBackoff is the delay before the first retry, doubled after each attempt
+	DefaultBackoff = 2 * time.Second
+
+	// sendTimeout bounds a single delivery attempt
+	sendTimeout = 10 * time.Second
+)
+
+// delivery is a queued notification
+type delivery struct {
+	name     string
+	notifier Notifier
+	event    Event
+	// attempt is the number of the next attempt, starting at 1
+	attempt int
+	// backoff is the delay before the attempt after the next one fails
+	backoff time.Duration
+}
+
+// Dispatcher sends notifications in the background, so a slow or failing sink never
+// blocks reconciliation. A pool of workers sends them, and a failed notification waits
+// for its retry off the queue, so one failing sink does not hold up the others.
+// It is a manager.Runnable and only delivers while started.
+type Dispatcher struct {
+	queue    chan delivery
+	retries  chan delivery
+	workers  int
+	attempts int
+	backoff  time.Duration
+}
+
+// NewDispatcher returns a Dispatcher buffering up to queueSize notifications
+func NewDispatcher(queueSize int) *Dispatcher {
+	return &Dispatcher{
+		queue:    make(chan delivery, queueSize),
+		retries:  make(chan delivery, queueSize),
+		workers:  DefaultWorkers,
+		attempts: DefaultAttempts,
+		backoff:  DefaultBackoff,
+	}
+}
+
+// WithRetry overrides the number of attempts and the initial backoff
+func (d *Dispatcher) WithRetry(attempts int, backoff time.Duration) *Dispatcher {
+	d.attempts = attempts
+	d.backoff = backoff
+	return d
+}
+
+// WithWorkers overrides the number of notifications sent at once
+func (d *Dispatcher) WithWorkers(workers int) *Dispatcher {
+	d.workers = max(workers, 1)
+	return d
+}
+
+// Send queues an event for a notifier without blocking. The event is dropped when the queue is full
+func (d *Dispatcher) Send(ctx context.Context, name string, notifier Notifier, event Event) {
+	select {
+	case d.queue <- delivery{name: name, notifier: notifier, event: event, attempt: 1, backoff: d.backoff}:
+	default:
+		logf.FromContext(ctx).Info("Notification queue full, dropping notification",
+			"notification", name, "trigger", event.Trigger)
+	}
+}
+
+// Start delivers queued notifications with the worker pool until ctx is cancelled
+func (d *Dispatcher) Start(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for range d.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.work(ctx)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// work sends queued notifications and retries until ctx is cancelled
+func (d *Dispatcher) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case item := <-d.retries:
+			d.deliver(ctx, item)
+		case item := <-d.queue:
+			d.deliver(ctx, item)
+		}
+	}
+}
+
+// deliver makes one attempt at sending a notification. A failed attempt is retried after
+// its backoff, which elapses off the worker so it can send other notifications meanwhile
+func (d *Dispatcher) deliver(ctx context.Context, item delivery) {
+	log := logf.Log.WithName("notify")
+
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	err := item.notifier.Notify(sendCtx, item.event)
+	cancel()
+	if err == nil {
+		return
+	}
+	if item.attempt >= d.attempts {
+		log.Error(err, "Giving up on notification",
+			"notification", item.name, "trigger", item.event.Trigger, "attempts", item.attempt)
+		return
+	}
+
+	delay := item.backoff
+	item.attempt++
+	item.backoff *= 2
+	time.AfterFunc(delay, func() {
+		select {
+		case d.retries <- item:
+		case <-ctx.Done():
+		default:
+			log.Error(err, "Retry queue full, giving up on notification",
+				"notification", item.name, "trigger", item.event.Trigger, "attempts", item.attempt-1)
+		}
+	})
+}
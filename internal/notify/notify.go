@@ -0,0 +1,215 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package notify sends rollout events to generic webhooks, Slack and MS Teams
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Payload formats
+const (
+	TypeWebhook = "webhook"
+	TypeSlack   = "slack"
+	TypeTeams   = "teams"
+)
+
+// defaultTemplate renders an event when the notification has no template
+const defaultTemplate = `[{{.Namespace}}/{{.Name}}] {{.Trigger}}: step {{.Step}} at {{.CanaryPercentage}}% ({{.Phase}}){{if .Message}} - {{.Message}}{{end}}`
+
+// Event is a rollout event sent to a notifier
+type Event struct {
+	Trigger          string    `json:"trigger"`
+	Name             string    `json:"name"`
+	Namespace        string    `json:"namespace"`
+	Phase            string    `json:"phase"`
+	Step             int       `json:"step"`
+	CanaryPercentage int       `json:"canaryPercentage"`
+	Message          string    `json:"message,omitempty"`
+	Time             time.Time `json:"time"`
+}
+
+// Notifier delivers an event to one sink
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// Config describes a notification sink
+type Config struct {
+	// Type is one of TypeWebhook, TypeSlack or TypeTeams
+	Type string
+	// URL is the webhook endpoint
+	URL string
+	// Token is sent as a bearer token by generic webhooks
+	Token string
+	// Template is a text/template rendering the message text
+	Template string
+}
+
+// New returns the Notifier for a Config
+func New(config Config, httpClient *http.Client) (Notifier, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("notification URL must not be empty")
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	text := config.Template
+	if text == "" {
+		text = defaultTemplate
+	}
+	tmpl, err := template.New("notification").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid notification template: %w", err)
+	}
+
+	sink := httpSink{client: httpClient, url: config.URL, template: tmpl}
+	switch config.Type {
+	case "", TypeWebhook:
+		return &webhookNotifier{httpSink: sink, token: config.Token}, nil
+	case TypeSlack:
+		return &slackNotifier{httpSink: sink}, nil
+	case TypeTeams:
+		return &teamsNotifier{httpSink: sink}, nil
+	default:
+		return nil, fmt.Errorf("unknown notification type %q", config.Type)
+	}
+}
+
+// httpSink posts JSON payloads to a webhook URL
+type httpSink struct {
+	client   *http.Client
+	url      string
+	template *template.Template
+}
+
+// render executes the message template for an event
+func (s *httpSink) render(event Event) (string, error) {
+	var text strings.Builder
+	if err := s.template.Execute(&text, event); err != nil {
+		return "", fmt.Errorf("failed to render notification: %w", err)
+	}
+	return text.String(), nil
+}
+
+// post sends payload as JSON and fails on any non-2xx response
+func (s *httpSink) post(ctx context.Context, payload any, header http.Header) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending notification: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("notification rejected with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// webhookNotifier posts the event itself with the rendered text
+type webhookNotifier struct {
+	httpSink
+	token string
+}
+
+// webhookPayload is the body of a generic webhook
+type webhookPayload struct {
+	Event
+	Text string `json:"text"`
+}
+
+// Notify posts the event to the webhook
+func (n *webhookNotifier) Notify(ctx context.Context, event Event) error {
+	text, err := n.render(event)
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	if n.token != "" {
+		header.Set("Authorization", "Bearer "+n.token)
+	}
+	return n.post(ctx, webhookPayload{Event: event, Text: text}, header)
+}
+
+// slackNotifier posts to a Slack incoming webhook
+type slackNotifier struct {
+	httpSink
+}
+
+// Notify posts the rendered text as a Slack message
+func (n *slackNotifier) Notify(ctx context.Context, event Event) error {
+	text, err := n.render(event)
+	if err != nil {
+		return err
+	}
+	return n.post(ctx, map[string]string{"text": text}, nil)
+}
+
+// teamsNotifier posts to an MS Teams incoming webhook
+type teamsNotifier struct {
+	httpSink
+}
+
+// teamsColors maps triggers to the accent color of the Teams card
+var teamsColors = map[string]string{
+	"RolloutCompleted":  "2EB67D",
+	"RolloutRolledBack": "ECB22E",
+	"RolloutFailed":     "E01E5A",
+}
+
+// Notify posts the rendered text as a Teams message card
+func (n *teamsNotifier) Notify(ctx context.Context, event Event) error {
+	text, err := n.render(event)
+	if err != nil {
+		return err
+	}
+
+	color, ok := teamsColors[event.Trigger]
+	if !ok {
+		color = "0076D7"
+	}
+	return n.post(ctx, map[string]string{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"summary":    fmt.Sprintf("%s %s/%s", event.Trigger, event.Namespace, event.Name),
+		"themeColor": color,
+		"text":       text,
+	}, nil)
+}
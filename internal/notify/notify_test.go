@@ -0,0 +1,189 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Notifiers", func() {
+	ctx := context.Background()
+	event := Event{
+		Trigger:          "RolloutRolledBack",
+		Name:             "my-app",
+		Namespace:        "default",
+		Phase:            "RolledBack",
+		Step:             2,
+		CanaryPercentage: 0,
+		Message:          "metrics exceeded thresholds",
+	}
+
+	var server *httptest.Server
+	var received chan map[string]any
+	var headers chan http.Header
+
+	BeforeEach(func() {
+		received = make(chan map[string]any, 10)
+		headers = make(chan http.Header, 10)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			payload := map[string]any{}
+			Expect(json.NewDecoder(r.Body).Decode(&payload)).To(Succeed())
+			received <- payload
+			headers <- r.Header
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should post the event and a bearer token to a generic webhook", func() {
+		notifier, err := New(Config{Type: TypeWebhook, URL: server.URL, Token: "s3cret"}, server.Client())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(notifier.Notify(ctx, event)).To(Succeed())
+
+		payload := <-received
+		Expect(payload).To(HaveKeyWithValue("trigger", "RolloutRolledBack"))
+		Expect(payload).To(HaveKeyWithValue("name", "my-app"))
+		Expect(payload).To(HaveKeyWithValue("text", "[default/my-app] RolloutRolledBack: step 2 at 0% (RolledBack) - metrics exceeded thresholds"))
+		Expect((<-headers).Get("Authorization")).To(Equal("Bearer s3cret"))
+	})
+
+	It("should post a Slack message rendered from the template", func() {
+		notifier, err := New(Config{Type: TypeSlack, URL: server.URL, Template: "{{.Name}} rolled back: {{.Message}}"}, server.Client())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(notifier.Notify(ctx, event)).To(Succeed())
+
+		Expect(<-received).To(Equal(map[string]any{"text": "my-app rolled back: metrics exceeded thresholds"}))
+	})
+
+	It("should post a Teams message card", func() {
+		notifier, err := New(Config{Type: TypeTeams, URL: server.URL}, server.Client())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(notifier.Notify(ctx, event)).To(Succeed())
+
+		payload := <-received
+		Expect(payload).To(HaveKeyWithValue("@type", "MessageCard"))
+		Expect(payload).To(HaveKeyWithValue("themeColor", "ECB22E"))
+		Expect(payload).To(HaveKeyWithValue("summary", "RolloutRolledBack default/my-app"))
+	})
+
+	It("should fail on a non-2xx response", func() {
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer failing.Close()
+
+		notifier, err := New(Config{Type: TypeSlack, URL: failing.URL}, failing.Client())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(notifier.Notify(ctx, event)).To(MatchError(ContainSubstring("status 500")))
+	})
+
+	DescribeTable("rejecting invalid configs",
+		func(config Config) {
+			_, err := New(config, nil)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("empty URL", Config{Type: TypeSlack}),
+		Entry("unknown type", Config{Type: "pager", URL: "http://example.com"}),
+		Entry("invalid template", Config{URL: "http://example.com", Template: "{{.Name"}),
+	)
+})
+
+var _ = Describe("Dispatcher", func() {
+	It("should retry failed notifications in the background", func() {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			// Fail the first two attempts
+			if calls.Add(1) <= 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer server.Close()
+
+		notifier, err := New(Config{Type: TypeSlack, URL: server.URL}, server.Client())
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		dispatcher := NewDispatcher(1).WithRetry(3, 10*time.Millisecond)
+		go func() {
+			defer GinkgoRecover()
+			Expect(dispatcher.Start(ctx)).To(Succeed())
+		}()
+
+		dispatcher.Send(ctx, "slack", notifier, Event{Trigger: "RolloutStarted"})
+		Eventually(calls.Load).Should(Equal(int32(3)))
+		Consistently(calls.Load, 100*time.Millisecond).Should(Equal(int32(3)))
+	})
+
+	It("should keep delivering to other sinks while a failing one waits for its retry", func() {
+		var failures, delivered atomic.Int32
+		failing := notifierFunc(func(context.Context, Event) error {
+			failures.Add(1)
+			return errors.New("connection refused")
+		})
+		working := notifierFunc(func(context.Context, Event) error {
+			delivered.Add(1)
+			return nil
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		dispatcher := NewDispatcher(10).WithRetry(4, time.Hour).WithWorkers(1)
+		go func() {
+			defer GinkgoRecover()
+			Expect(dispatcher.Start(ctx)).To(Succeed())
+		}()
+
+		dispatcher.Send(ctx, "failing", failing, Event{Trigger: "RolloutStarted"})
+		dispatcher.Send(ctx, "working", working, Event{Trigger: "RolloutStarted"})
+		Eventually(delivered.Load).Should(Equal(int32(1)))
+		Expect(failures.Load()).To(Equal(int32(1)))
+	})
+
+	It("should drop notifications instead of blocking when the queue is full", func() {
+		dispatcher := NewDispatcher(1)
+		notifier, err := New(Config{URL: "http://127.0.0.1:1"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			dispatcher.Send(context.Background(), "a", notifier, Event{})
+			dispatcher.Send(context.Background(), "b", notifier, Event{})
+		}()
+		Eventually(done).Should(BeClosed())
+		Expect(dispatcher.queue).To(HaveLen(1))
+	})
+})
+
+// notifierFunc adapts a function to a Notifier
+type notifierFunc func(ctx context.Context, event Event) error
+
+func (f notifierFunc) Notify(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}